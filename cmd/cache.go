@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// labelProject is the label with the directory of the project a cache
+	// volume belongs to.
+	labelProject = "com.ultimatesoftware.envctl.project"
+	// labelCache is the label with the path a cache volume is mounted at.
+	labelCache = "com.ultimatesoftware.envctl.cache"
+)
+
+var volumeUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func newCacheCmd(ctl container.Controller) *cobra.Command {
+	cacheDesc := "manage the cache volumes for the current project"
+
+	cacheLongDesc := `cache - Manage the cache volumes for the current project
+
+Every path in the "caches" section of the config file gets a volume of its own,
+which belongs to the project rather than to any one environment. Destroying an
+environment keeps its caches around, so that the next one created for the
+project starts out with them.`
+
+	cacheLsDesc := "list the cache volumes for the current project"
+
+	cacheLsLongDesc := `ls - List the cache volumes for the current project`
+
+	cachePruneDesc := "remove cache volumes for the current project"
+
+	cachePruneLongDesc := `prune - Remove cache volumes for the current project
+
+"prune" removes the cache volumes for the paths it's given, or all of the
+project's cache volumes if it isn't given any. Caches that are mounted in an
+environment can't be removed until it's destroyed.`
+
+	msgNoCaches := `There aren't any caches for this project.
+
+Add paths to the "caches" section of the config file to create some.`
+
+	runLs := func(cmd *cobra.Command, args []string) {
+		vols := listCaches(ctl)
+		if len(vols) == 0 {
+			fmt.Println(msgNoCaches)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tPATH")
+		for _, v := range vols {
+			fmt.Fprintf(w, "%v\t%v\n", v.Name, v.Labels[labelCache])
+		}
+		w.Flush()
+	}
+
+	runPrune := func(cmd *cobra.Command, args []string) {
+		paths := map[string]bool{}
+		for _, arg := range args {
+			paths[path.Clean(arg)] = true
+		}
+
+		failed := false
+		for _, v := range listCaches(ctl) {
+			if len(paths) > 0 && !paths[v.Labels[labelCache]] {
+				continue
+			}
+
+			fmt.Printf("removing %v...\n", v.Name)
+
+			err := ctl.(container.VolumeManager).RemoveVolume(v.Name)
+			if err != nil {
+				fmt.Printf("error removing cache %v: %v\n", v.Name, err)
+				failed = true
+			}
+		}
+
+		if failed {
+			osExit(1)
+		}
+	}
+
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: cacheDesc,
+		Long:  cacheLongDesc,
+	}
+
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "ls",
+		Short: cacheLsDesc,
+		Long:  cacheLsLongDesc,
+		Run:   runLs,
+	})
+
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "prune [path...]",
+		Short: cachePruneDesc,
+		Long:  cachePruneLongDesc,
+		Run:   runPrune,
+	})
+
+	return cacheCmd
+}
+
+// listCaches returns the cache volumes for the project in the current
+// directory, exiting if they can't be listed.
+func listCaches(ctl container.Controller) []container.Volume {
+	vm, ok := ctl.(container.VolumeManager)
+	if !ok {
+		fmt.Println("error listing caches: the runtime can't manage volumes")
+		osExit(1)
+		return nil
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("error getting current working directory: %v\n", err)
+		osExit(1)
+		return nil
+	}
+
+	vols, err := vm.Volumes(map[string]string{labelProject: pwd})
+	if err != nil {
+		fmt.Printf("error listing caches: %v\n", err)
+		osExit(1)
+		return nil
+	}
+
+	return vols
+}
+
+// getCaches creates the volumes for the caches of the project in pwd, unless
+// they already exist, and returns the mounts for them.
+func getCaches(
+	ctl container.Controller,
+	caches []string,
+	pwd string,
+) ([]container.Mount, error) {
+	mounts := []container.Mount{}
+	if len(caches) == 0 {
+		return mounts, nil
+	}
+
+	vm, ok := ctl.(container.VolumeManager)
+	if !ok {
+		return nil, fmt.Errorf("the runtime can't manage volumes")
+	}
+
+	for _, c := range caches {
+		v := cacheVolume(pwd, c)
+		if err := vm.CreateVolume(v); err != nil {
+			return nil, err
+		}
+
+		mounts = append(mounts, container.Mount{
+			Type:        container.MountVolume,
+			Source:      v.Name,
+			Destination: v.Labels[labelCache],
+		})
+	}
+
+	return mounts, nil
+}
+
+// cacheVolume returns the volume for the cache at the given path, for the
+// project in pwd. Its name is readable, but also includes a hash of pwd, so
+// that projects with the same directory name don't share caches.
+func cacheVolume(pwd string, cache string) container.Volume {
+	cache = path.Clean(cache)
+
+	sum := sha256.Sum256([]byte(pwd))
+	name := fmt.Sprintf(
+		"envctl-%v-%x-%v",
+		volumeUnsafe.ReplaceAllString(filepath.Base(pwd), "-"),
+		sum[:4],
+		volumeUnsafe.ReplaceAllString(strings.TrimPrefix(cache, "/"), "-"),
+	)
+
+	return container.Volume{
+		Name: name,
+		Labels: map[string]string{
+			labelProject: pwd,
+			labelCache:   cache,
+		},
+	}
+}
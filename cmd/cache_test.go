@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	"github.com/spf13/cobra"
+)
+
+// runCmd runs cmd with args, and returns what it printed.
+func runCmd(t test_pkg.T, cmd *cobra.Command, args ...string) string {
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, args)
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case out := <-outch:
+		return string(out)
+	}
+
+	return ""
+}
+
+func TestCaches(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:  "alpine",
+			Shell:  "/bin/sh",
+			Caches: []string{"/root/.npm", "/root/.m2/"},
+		},
+	}
+
+	pwd, _ := os.Getwd()
+	npm := cacheVolume(pwd, "/root/.npm")
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	env, _ := s.Read(envName)
+	expected := []container.Mount{
+		{
+			Type:        container.MountVolume,
+			Source:      npm.Name,
+			Destination: "/root/.npm",
+		},
+		{
+			Type:        container.MountVolume,
+			Source:      cacheVolume(pwd, "/root/.m2").Name,
+			Destination: "/root/.m2",
+		},
+	}
+
+	actual := env.Container.Mounts
+	if len(expected) != len(actual) {
+		t.Fatal("mounts", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatal("mounts", expected, actual)
+		}
+	}
+
+	cacheCmd := newCacheCmd(ctl)
+	ls, _, _ := cacheCmd.Find([]string{"ls"})
+	prune, _, _ := cacheCmd.Find([]string{"prune"})
+
+	out := runCmd(t, ls)
+	if !strings.Contains(out, npm.Name) || !strings.Contains(out, "/root/.m2") {
+		t.Fatal("cache ls output", "both caches", out)
+	}
+
+	// The caches are in use until the environment is destroyed.
+	runCmd(t, prune)
+	if 1 != exitCode {
+		t.Fatal("exit code pruning caches in use", 1, exitCode)
+	}
+	exitCode = 0
+
+	runCmd(t, newDestroyCmd(ctl, s))
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	vols, _ := ctl.Volumes(nil)
+	if 2 != len(vols) {
+		t.Fatal("volumes after re-creating", 2, len(vols))
+	}
+
+	runCmd(t, newDestroyCmd(ctl, s))
+	runCmd(t, prune, "/root/.npm")
+
+	vols, _ = ctl.Volumes(nil)
+	if 1 != len(vols) || npm.Name == vols[0].Name {
+		t.Fatal("volumes after pruning /root/.npm", "the .m2 cache", vols)
+	}
+
+	runCmd(t, prune)
+
+	vols, _ = ctl.Volumes(nil)
+	if 0 != len(vols) {
+		t.Fatal("volumes after pruning", 0, len(vols))
+	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+}
+
+func TestCacheVolume(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	v := cacheVolume("/home/me/src/my repo", "/root/.cache/pip/")
+
+	if !strings.HasPrefix(v.Name, "envctl-my-repo-") ||
+		!strings.HasSuffix(v.Name, "-root-.cache-pip") {
+
+		t.Fatal("volume name", "envctl-my-repo-<hash>-root-.cache-pip", v.Name)
+	}
+
+	other := cacheVolume("/home/you/src/my repo", "/root/.cache/pip")
+	if v.Name == other.Name {
+		t.Fatal("volume names for different projects", "different", other.Name)
+	}
+
+	if "/root/.cache/pip" != v.Labels[labelCache] {
+		t.Fatal("cache label", "/root/.cache/pip", v.Labels[labelCache])
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/UltimateSoftware/envctl/internal/config"
-	"github.com/UltimateSoftware/envctl/internal/db"
 	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
@@ -22,7 +24,13 @@ func newCreateCmd(
 	createLongDesc := `create - Create an instance of a development environment
 
 "create" will dynamically build a development environment based on the settings
-in the config file. Only one environment can exist at any time per config file.
+in the config file. Several environments can exist side by side for the same
+config file, as long as they have different names. The name is chosen with the
+global "--env" flag, and defaults to "default".
+
+If the config file has a "ready" probe, "create" waits for it to pass after
+bootstrap before reporting the environment as ready. If it doesn't pass in
+time, the environment is put into "error" state.
 `
 
 	msgEnvReady := `There is already an environment ready for use!
@@ -30,21 +38,24 @@ in the config file. Only one environment can exist at any time per config file.
 To use it, run "envctl login", or destroy it with "envctl destroy".`
 
 	runCreate := func(cmd *cobra.Command, args []string) {
-		env, err := s.Read()
+		env, err := s.Read(envName)
 		if err != nil {
 			fmt.Printf("error reading environment state: %v\n", err)
-			os.Exit(1)
+			osExit(1)
+			return
 		}
 
 		if env.Initialized() {
 			fmt.Println(msgEnvReady)
-			os.Exit(1)
+			osExit(1)
+			return
 		}
 
 		cfg, err := l.Load()
 		if err != nil {
 			fmt.Printf("error reading config file: %v\n", err)
-			os.Exit(1)
+			osExit(1)
+			return
 		}
 
 		name := uuid.New().String()
@@ -60,13 +71,76 @@ To use it, run "envctl login", or destroy it with "envctl destroy".`
 		envs, err := parseVariables(cfg)
 		if err != nil {
 			fmt.Printf("error getting environment variables: %v\n", err)
-			os.Exit(1)
+			osExit(1)
+			return
+		}
+
+		ports, err := cfg.Ports.Bindings()
+		if err != nil {
+			fmt.Printf("error getting ports: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		resources, err := cfg.Resources.Limits()
+		if err != nil {
+			fmt.Printf("error getting resource limits: %v\n", err)
+			osExit(1)
+			return
 		}
 
 		pwd, err := os.Getwd()
 		if err != nil {
 			fmt.Printf("error getting current working directory: %v\n", err)
-			os.Exit(1)
+			osExit(1)
+			return
+		}
+
+		hash, err := config.Hash(cfg, pwd)
+		if err != nil {
+			fmt.Printf("error hashing config: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		mounts, err := getMounts(cfg.Mounts, pwd)
+		if err != nil {
+			fmt.Printf("error getting mounts: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		caches, err := getCaches(ctl, cfg.Caches, pwd)
+		if err != nil {
+			fmt.Printf("error creating caches: %v\n", err)
+			osExit(1)
+			return
+		}
+		mounts = append(mounts, caches...)
+
+		services, err := getServices(cfg.Services, pwd)
+		if err != nil {
+			fmt.Printf("error getting services: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		var build *container.Build
+		if cfg.Build != nil {
+			build = &container.Build{
+				Context:    cfg.Build.Context,
+				Dockerfile: cfg.Build.Dockerfile,
+				Args:       cfg.Build.Args,
+				Target:     cfg.Build.Target,
+			}
+
+			if !filepath.IsAbs(build.Context) {
+				build.Context = filepath.Join(pwd, build.Context)
+			}
+
+			if build.Dockerfile == "" {
+				build.Dockerfile = "Dockerfile"
+			}
 		}
 
 		meta := container.Metadata{
@@ -77,10 +151,35 @@ To use it, run "envctl login", or destroy it with "envctl destroy".`
 				Source:      pwd,
 				Destination: mount,
 			},
-			Envs:    envs,
-			NoCache: !(*cfg.CacheImage),
-			User:    cfg.User,
-			Ports:   cfg.Ports,
+			Mounts:    mounts,
+			Envs:      envs,
+			NoCache:   !(*cfg.CacheImage),
+			User:      cfg.User,
+			Ports:     ports,
+			Build:     build,
+			Resources: resources,
+			Services:  services,
+			Hostname:  cfg.Network.Hostname,
+			Aliases:   cfg.Network.Aliases,
+
+			ExternalNetworks: cfg.Network.External,
+		}
+
+		if cfg.Ready != nil {
+			probe, err := cfg.Ready.Probe()
+			if err != nil {
+				fmt.Printf("error getting ready probe: %v\n", err)
+				osExit(1)
+				return
+			}
+
+			meta.Ready = &probe
+		}
+
+		rawcmds := cfg.Bootstrap
+		if cfg.BakeBootstrap {
+			meta.Bootstrap = rawcmds
+			rawcmds = nil
 		}
 
 		fmt.Println("creating your environment...")
@@ -88,10 +187,10 @@ To use it, run "envctl login", or destroy it with "envctl destroy".`
 		newMeta, err := ctl.Create(meta)
 		if err != nil {
 			fmt.Printf("error creating environment: %v\n", err)
-			os.Exit(1)
+			osExit(1)
+			return
 		}
 
-		rawcmds := cfg.Bootstrap
 		if len(rawcmds) > 0 {
 			fmt.Println("running bootstrap steps...")
 
@@ -100,51 +199,124 @@ To use it, run "envctl login", or destroy it with "envctl destroy".`
 				_, err := script.WriteString(fmt.Sprintf("%v\n", rawcmd))
 				if err != nil {
 					fmt.Printf("error generating bootstrap script: %v\n", err)
-					s.Create(db.Environment{
-						Status:    db.StatusError,
-						Container: newMeta,
+					s.Create(envName, db.Environment{
+						Status:     db.StatusError,
+						Container:  newMeta,
+						ConfigHash: hash,
 					})
-					os.Exit(1)
+					osExit(1)
+					return
 				}
 			}
 
+			// The directory is gone if a rebuild just deleted the only
+			// environment in it.
+			if err := os.MkdirAll(".envctl", os.ModePerm|os.ModeDir); err != nil {
+				fmt.Printf("error creating tmp script directory: %v\n", err)
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
+				})
+				osExit(1)
+				return
+			}
+
 			fname := ".envctl/" + uuid.New().String()
 			f, err := os.OpenFile(fname, os.O_CREATE|os.O_RDWR, os.ModePerm)
 			if err != nil {
 				fmt.Printf("error opening tmp script for writing: %v\n", err)
-				os.Exit(1)
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
+				})
+				osExit(1)
+				return
 			}
 
-			if _, err := io.Copy(f, script); err != nil {
+			_, err = io.Copy(f, script)
+			f.Close()
+			if err != nil {
 				fmt.Printf("error writing bootstrap script: %v\n", err)
-				s.Create(db.Environment{
-					Status:    db.StatusError,
-					Container: newMeta,
+				os.Remove(fname)
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
 				})
-				os.Exit(1)
+				osExit(1)
+				return
 			}
 
 			cmdarr := []string{shell, fname}
 
-			err = ctl.Run(newMeta, cmdarr)
+			// Bootstrap runs without a TTY so that its stderr stays separate
+			// from its stdout, and it doesn't swallow the user's input.
+			err = ctl.Run(newMeta, cmdarr, container.RunOpts{})
+			os.Remove(fname)
+			if exitErr, ok := err.(*container.ExitError); ok {
+				fmt.Printf("bootstrap failed with exit code %v\n", exitErr.Code)
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
+				})
+				osExit(1)
+				return
+			}
+
 			if err != nil {
 				fmt.Printf("error running %v: %v\n", cmdarr, err)
-				s.Create(db.Environment{
-					Status:    db.StatusError,
-					Container: newMeta,
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
 				})
-				os.Exit(1)
+				osExit(1)
+				return
+			}
+		}
+
+		if newMeta.Ready != nil {
+			// Creating the container doesn't start it, and only bootstrap
+			// steps that aren't baked in do, but whatever the probe checks
+			// can't be up until it's running.
+			if err := ctl.Start(newMeta); err != nil {
+				fmt.Printf("error starting environment: %v\n", err)
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
+				})
+				osExit(1)
+				return
+			}
+
+			fmt.Println("waiting for the environment to be ready...")
+
+			if err := waitReady(ctl, newMeta); err != nil {
+				fmt.Printf("environment isn't ready: %v\n", err)
+				s.Create(envName, db.Environment{
+					Status:     db.StatusError,
+					Container:  newMeta,
+					ConfigHash: hash,
+				})
+				osExit(1)
+				return
 			}
 		}
 
 		fmt.Println("saving environment...")
-		err = s.Create(db.Environment{
-			Status:    db.StatusReady,
-			Container: newMeta,
+		err = s.Create(envName, db.Environment{
+			Status:     db.StatusReady,
+			Container:  newMeta,
+			ConfigHash: hash,
 		})
 		if err != nil {
 			fmt.Printf("error saving environment: %v\n", err)
-			os.Exit(1)
+			osExit(1)
+			return
 		}
 	}
 
@@ -156,15 +328,125 @@ To use it, run "envctl login", or destroy it with "envctl destroy".`
 	}
 }
 
+// getMounts turns the mounts in the config file into mounts for the
+// container. Bind mount sources starting with "~" are in the user's home
+// directory, and relative ones are relative to pwd.
+func getMounts(cfgmounts []config.Mount, pwd string) ([]container.Mount, error) {
+	mounts := []container.Mount{}
+
+	for _, m := range cfgmounts {
+		mnt := container.Mount{
+			Type:        container.MountType(m.Type),
+			Source:      m.Source,
+			Destination: m.Target,
+			ReadOnly:    m.ReadOnly,
+		}
+
+		if mnt.Type == "" {
+			mnt.Type = container.MountBind
+		}
+
+		if mnt.Type == container.MountBind {
+			src := mnt.Source
+			if src == "~" || strings.HasPrefix(src, "~/") {
+				home, err := os.UserHomeDir()
+				if err != nil {
+					return nil, err
+				}
+
+				src = filepath.Join(home, src[1:])
+			}
+
+			if !filepath.IsAbs(src) {
+				src = filepath.Join(pwd, src)
+			}
+
+			mnt.Source = src
+		}
+
+		mounts = append(mounts, mnt)
+	}
+
+	return mounts, nil
+}
+
+// getServices turns the services in the config file into services for the
+// container, in the order to start them in. Relative bind mount sources are
+// relative to pwd.
+func getServices(
+	cfgsvcs map[string]config.Service,
+	pwd string,
+) ([]container.Service, error) {
+	names, err := config.ServiceOrder(cfgsvcs)
+	if err != nil {
+		return nil, err
+	}
+
+	svcs := []container.Service{}
+	for _, name := range names {
+		s := cfgsvcs[name]
+
+		envs, err := evalVariables(s.Variables)
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %v", name, err)
+		}
+
+		ports, err := s.Ports.Bindings()
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %v", name, err)
+		}
+
+		vols := []config.Mount{}
+		for _, spec := range s.Volumes {
+			vol, err := config.ParseVolume(spec)
+			if err != nil {
+				return nil, fmt.Errorf("service %v: %v", name, err)
+			}
+
+			vols = append(vols, vol)
+		}
+
+		mounts, err := getMounts(vols, pwd)
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %v", name, err)
+		}
+
+		svc := container.Service{
+			Name:      name,
+			Image:     s.Image,
+			Envs:      envs,
+			Ports:     ports,
+			Mounts:    mounts,
+			DependsOn: s.DependsOn,
+		}
+
+		if s.Healthcheck != nil {
+			hc, err := s.Healthcheck.Parse()
+			if err != nil {
+				return nil, fmt.Errorf("service %v: %v", name, err)
+			}
+
+			svc.Healthcheck = &hc
+		}
+
+		svcs = append(svcs, svc)
+	}
+
+	return svcs, nil
+}
+
 func parseVariables(cfg config.Opts) ([]string, error) {
-	rawenvs := cfg.Variables
+	return evalVariables(cfg.Variables)
+}
 
+func evalVariables(rawenvs map[string]string) ([]string, error) {
 	// This supports dynamic evaluation of environment variables so secrets
 	// don't have to be checked into the repo, but config files don't have
-	// to be generated from templates either.
+	// to be generated from templates either. With bake_bootstrap, they're
+	// saved in the image, though.
 	envs := []string{}
 	for k, v := range rawenvs {
-		if v[0] == '$' {
+		if len(v) > 0 && v[0] == '$' {
 			v = os.Getenv(v[1:])
 		}
 
@@ -2,22 +2,23 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/UltimateSoftware/envctl/internal/config"
-	"github.com/UltimateSoftware/envctl/internal/db"
 	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/UltimateSoftware/envctl/test_pkg"
 )
 
 func TestCreate(got *testing.T) {
 	t := test_pkg.NewT(got)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
-		},
-	}
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
 	cfg := memConfig{
 		opts: config.Opts{
@@ -53,70 +54,70 @@ func TestCreate(got *testing.T) {
 	}
 
 	// Testing that the user-specified configuration is saved correctly.
-	if expectedStatus != s.env.Status {
-		t.Fatal("environment status", expectedStatus, s.env.Status)
+	if expectedStatus != s.env().Status {
+		t.Fatal("environment status", expectedStatus, s.env().Status)
 	}
 
-	if expectedContainer.BaseImage != s.env.Container.BaseImage {
+	if expectedContainer.BaseImage != s.env().Container.BaseImage {
 		t.Fatal("environment image",
-			expectedContainer.BaseImage, s.env.Container.BaseImage)
+			expectedContainer.BaseImage, s.env().Container.BaseImage)
 	}
 
-	if expectedContainer.Shell != s.env.Container.Shell {
+	if expectedContainer.Shell != s.env().Container.Shell {
 		t.Fatal("environment shell",
-			expectedContainer.Shell, s.env.Container.Shell)
+			expectedContainer.Shell, s.env().Container.Shell)
 	}
 
 	if expectedContainer.Mount.Destination !=
-		s.env.Container.Mount.Destination {
+		s.env().Container.Mount.Destination {
 
 		t.Fatal(
 			"environment mount point",
 			expectedContainer.Mount.Destination,
-			s.env.Container.Mount.Destination,
+			s.env().Container.Mount.Destination,
 		)
 	}
 
 	// Now that correct saving of user-specified configuration has been
 	// established, the calls to the container engine can be tested to make
 	// sure that what's done there is totally in sync with what's been saved.
-	if s.env.Container.ID != ctl.current.ID {
-		t.Fatal("container id", s.env.Container.ID, ctl.current.ID)
+	if s.env().Container.ID != ctl.current.ID {
+		t.Fatal("container id", s.env().Container.ID, ctl.current.ID)
 	}
 
-	if s.env.Container.ImageID != ctl.current.ImageID {
+	if s.env().Container.ImageID != ctl.current.ImageID {
 		t.Fatal(
 			"container image id",
-			s.env.Container.ImageID,
+			s.env().Container.ImageID,
 			ctl.current.ImageID,
 		)
 	}
 
-	if s.env.Container.BaseImage != ctl.current.BaseImage {
+	if s.env().Container.BaseImage != ctl.current.BaseImage {
 		t.Fatal(
 			"container base image",
-			s.env.Container.BaseImage,
+			s.env().Container.BaseImage,
 			ctl.current.BaseImage,
 		)
 	}
 
-	if s.env.Container.BaseName != ctl.current.BaseName {
+	if s.env().Container.BaseName != ctl.current.BaseName {
 		t.Fatal("container base name",
-			s.env.Container.BaseName,
+			s.env().Container.BaseName,
 			ctl.current.BaseName,
 		)
 	}
 
-	if s.env.Container.Shell != ctl.current.Shell {
+	if s.env().Container.Shell != ctl.current.Shell {
 		t.Fatal("container shell",
-			s.env.Container.Shell,
+			s.env().Container.Shell,
 			ctl.current.Shell,
 		)
 	}
 
-	if s.env.Container.Mount.Destination != ctl.current.Mount.Destination {
+	if s.env().Container.Mount.Destination != ctl.current.Mount.Destination {
 		t.Fatal("container mount point",
-			s.env.Container.Mount.Destination,
+			s.env().Container.Mount.Destination,
 			ctl.current.Mount.Destination,
 		)
 	}
@@ -131,11 +132,9 @@ func TestCreate(got *testing.T) {
 func TestCreateWithVariables(got *testing.T) {
 	t := test_pkg.NewT(got)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
-		},
-	}
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
 	cfg := memConfig{
 		opts: config.Opts{
@@ -165,19 +164,17 @@ func TestCreateWithVariables(got *testing.T) {
 	}
 
 	expected := "foo=bar"
-	if s.env.Container.Envs[0] != expected {
-		t.Fatal("variables", expected, s.env.Container.Envs[0])
+	if s.env().Container.Envs[0] != expected {
+		t.Fatal("variables", expected, s.env().Container.Envs[0])
 	}
 }
 
 func TestCreateWithDynamicVariables(got *testing.T) {
 	t := test_pkg.NewT(got)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
-		},
-	}
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
 	cfg := memConfig{
 		opts: config.Opts{
@@ -210,8 +207,8 @@ func TestCreateWithDynamicVariables(got *testing.T) {
 	}
 
 	expected := "ENVCTL_TESTING=FOO"
-	if s.env.Container.Envs[0] != expected {
-		t.Fatal("variables", expected, s.env.Container.Envs[0])
+	if s.env().Container.Envs[0] != expected {
+		t.Fatal("variables", expected, s.env().Container.Envs[0])
 	}
 }
 
@@ -251,11 +248,9 @@ func TestNoCache(got *testing.T) {
 
 	ctl := newMockCtl(nil)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
-		},
-	}
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
 	cmd := newCreateCmd(ctl, s, cfg)
 
@@ -271,8 +266,8 @@ func TestNoCache(got *testing.T) {
 	case <-outch:
 	}
 
-	if s.env.Container.NoCache != true {
-		t.Fatal("setting nocache", true, s.env.Container.NoCache)
+	if s.env().Container.NoCache != true {
+		t.Fatal("setting nocache", true, s.env().Container.NoCache)
 	}
 }
 
@@ -291,11 +286,9 @@ func TestAlternateUser(got *testing.T) {
 
 	ctl := newMockCtl(nil)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
-		},
-	}
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
 	cmd := newCreateCmd(ctl, s, cfg)
 
@@ -311,8 +304,8 @@ func TestAlternateUser(got *testing.T) {
 	case <-outch:
 	}
 
-	if s.env.Container.User != "foouser" {
-		t.Fatal("setting user", "foouser", s.env.Container.User)
+	if s.env().Container.User != "foouser" {
+		t.Fatal("setting user", "foouser", s.env().Container.User)
 	}
 }
 
@@ -326,25 +319,345 @@ func TestPortMappings(got *testing.T) {
 			Mount:      "/foo/mnt",
 			CacheImage: config.NoCacheImage,
 			User:       "foouser",
-			Ports: config.L3Ports(map[string][]int{
-				"tcp": []int{
-					99999,
-				},
-				"udp": []int{
-					88888,
+			Ports: config.Ports{
+				"4567",
+				"127.0.0.1:8080:80/udp",
+				"auto:3000-3001",
+			},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	expected := []container.PortBinding{
+		{HostPort: 4567, ContainerPort: 4567, Protocol: "tcp"},
+		{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "udp"},
+		{ContainerPort: 3000, Protocol: "tcp"},
+		{ContainerPort: 3001, Protocol: "tcp"},
+	}
+
+	actual := s.env().Container.Ports
+	if len(expected) != len(actual) {
+		t.Fatal("saving ports", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatal("saving ports", expected, actual)
+		}
+	}
+}
+
+func TestBootstrapWithoutTTY(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:     "test",
+			Shell:     "/foo/sh",
+			Mount:     "/foo/mnt",
+			Bootstrap: []string{"echo foo"},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+
+	var ranWith *container.RunOpts
+	ctl.runFn = func(
+		m container.Metadata,
+		cmds []string,
+		opts container.RunOpts,
+	) error {
+		ranWith = &opts
+		return nil
+	}
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if ranWith == nil {
+		t.Fatal("running bootstrap", "bootstrap run", nil)
+	}
+
+	if ranWith.TTY || ranWith.Stdin {
+		t.Fatal("bootstrap run options", container.RunOpts{}, *ranWith)
+	}
+
+	if db.StatusReady != s.env().Status {
+		t.Fatal("status", db.StatusReady, s.env().Status)
+	}
+}
+
+func TestBootstrapFailure(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:     "test",
+			Shell:     "/foo/sh",
+			Bootstrap: []string{"make deps"},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+	ctl.runFn = func(
+		m container.Metadata,
+		cmds []string,
+		opts container.RunOpts,
+	) error {
+		return &container.ExitError{Cmd: cmds, Code: 3}
+	}
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	out := runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	expected := "bootstrap failed with exit code 3"
+	if !strings.Contains(out, expected) {
+		t.Fatal("create output", expected, out)
+	}
+
+	if 1 != exitCode {
+		t.Fatal("exit code", 1, exitCode)
+	}
+
+	// The container is kept track of, so that it can be destroyed.
+	env := s.env()
+	if db.StatusError != env.Status || ctl.current.ID != env.Container.ID {
+		t.Fatal("stored environment", "the container in error state", env)
+	}
+}
+
+func TestCreateNamedEnvironment(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusReady,
+	})
+
+	envName = "ci"
+	defer func() { envName = db.DefaultName }()
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/foo/sh",
+			Mount: "/foo/mnt",
+		},
+	}
+
+	ctl := newMockCtl(nil)
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if db.StatusReady != s.env().Status {
+		t.Fatal("named environment status", db.StatusReady, s.env().Status)
+	}
+
+	if ctl.current.ID != s.env().Container.ID {
+		t.Fatal("named environment container",
+			ctl.current.ID,
+			s.env().Container.ID,
+		)
+	}
+
+	envs, _ := s.List()
+	if len(envs) != 2 {
+		t.Fatal("number of environments", 2, len(envs))
+	}
+}
+
+func TestCreateWithBuild(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Shell: "/foo/sh",
+			Mount: "/foo/mnt",
+			Build: &config.Build{
+				Context: "docker",
+				Args: map[string]string{
+					"FOO": "bar",
 				},
-			}),
+				Target: "dev",
+			},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	pwd, _ := os.Getwd()
+
+	build := ctl.current.Build
+	if build == nil {
+		t.Fatal("build settings", "build settings", nil)
+	}
+
+	if filepath.Join(pwd, "docker") != build.Context {
+		t.Fatal("build context", filepath.Join(pwd, "docker"), build.Context)
+	}
+
+	if "Dockerfile" != build.Dockerfile {
+		t.Fatal("default Dockerfile", "Dockerfile", build.Dockerfile)
+	}
+
+	if "bar" != build.Args["FOO"] {
+		t.Fatal("build args", "bar", build.Args["FOO"])
+	}
+
+	if "dev" != build.Target {
+		t.Fatal("build target", "dev", build.Target)
+	}
+}
+
+func TestCreateBakeBootstrap(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:         "test",
+			Shell:         "/foo/sh",
+			Mount:         "/foo/mnt",
+			Bootstrap:     []string{"make deps"},
+			BakeBootstrap: true,
 		},
 	}
 
 	ctl := newMockCtl(nil)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
+	ran := false
+	ctl.runFn = func(
+		m container.Metadata,
+		cmds []string,
+		opts container.RunOpts,
+	) error {
+		ran = true
+		return nil
+	}
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if ran {
+		t.Fatal("running baked bootstrap steps", false, ran)
+	}
+
+	if len(ctl.current.Bootstrap) != 1 ||
+		ctl.current.Bootstrap[0] != "make deps" {
+
+		t.Fatal("baked bootstrap steps", []string{"make deps"},
+			ctl.current.Bootstrap)
+	}
+
+	if db.StatusReady != s.env().Status {
+		t.Fatal("status", db.StatusReady, s.env().Status)
+	}
+}
+
+func TestCreateWithMounts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/foo/sh",
+			Mounts: []config.Mount{
+				{Source: "~/.ssh", Target: "/root/.ssh", ReadOnly: true},
+				{Source: "cache", Target: "/cache"},
+				{Type: "volume", Source: "m2", Target: "/root/.m2"},
+				{Type: "tmpfs", Target: "/tmp"},
+			},
 		},
 	}
 
+	ctl := newMockCtl(nil)
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
 	cmd := newCreateCmd(ctl, s, cfg)
 
 	// Hijacking here swallows the command output so that it doesn't clutter
@@ -359,23 +672,233 @@ func TestPortMappings(got *testing.T) {
 	case <-outch:
 	}
 
-	t.Logf("%v", s.env.Container.Ports)
+	home, _ := os.UserHomeDir()
+	pwd, _ := os.Getwd()
+
+	expected := []container.Mount{
+		{
+			Type:        container.MountBind,
+			Source:      filepath.Join(home, ".ssh"),
+			Destination: "/root/.ssh",
+			ReadOnly:    true,
+		},
+		{
+			Type:        container.MountBind,
+			Source:      filepath.Join(pwd, "cache"),
+			Destination: "/cache",
+		},
+		{
+			Type:        container.MountVolume,
+			Source:      "m2",
+			Destination: "/root/.m2",
+		},
+		{
+			Type:        container.MountTmpfs,
+			Destination: "/tmp",
+		},
+	}
+
+	actual := ctl.current.Mounts
+	if len(expected) != len(actual) {
+		t.Fatal("mounts", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatal("mounts", expected, actual)
+		}
+	}
+}
+
+func TestCreateWithResources(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/foo/sh",
+			Resources: config.Resources{
+				CPUs:      2,
+				Memory:    "2g",
+				PidsLimit: 512,
+				ShmSize:   "256m",
+			},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
-	tcp, ok := s.env.Container.Ports["tcp"]
-	if !ok {
-		t.Fatal("saving ports", true, ok)
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	expected := container.Resources{
+		NanoCPUs:  2000000000,
+		Memory:    2 << 30,
+		PidsLimit: 512,
+		ShmSize:   256 << 20,
+	}
+
+	if expected != ctl.current.Resources {
+		t.Fatal("resources", expected, ctl.current.Resources)
+	}
+
+	if expected != s.env().Container.Resources {
+		t.Fatal("saved resources", expected, s.env().Container.Resources)
+	}
+}
+
+func TestCreateWithServices(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	os.Setenv("ENVCTL_TEST_DB_PASSWORD", "secret")
+	defer os.Unsetenv("ENVCTL_TEST_DB_PASSWORD")
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/foo/sh",
+			Services: map[string]config.Service{
+				"db": {
+					Image: "postgres:11",
+					Variables: map[string]string{
+						"POSTGRES_PASSWORD": "$ENVCTL_TEST_DB_PASSWORD",
+					},
+					Healthcheck: &config.Healthcheck{
+						Test:     config.Command{"CMD", "pg_isready"},
+						Interval: "2s",
+					},
+				},
+				"cache": {
+					Image:     "redis:5",
+					Ports:     config.Ports{"6379"},
+					Volumes:   []string{"./redis.conf:/etc/redis.conf:ro", "/data"},
+					DependsOn: []string{"db"},
+				},
+			},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal("getting current working directory", nil, err)
+	}
+
+	// The cache depends on the db, so it comes after it even though it sorts
+	// before it.
+	svcs := s.env().Container.Services
+	if len(svcs) != 2 || "db" != svcs[0].Name || "cache" != svcs[1].Name {
+		t.Fatal("services", "db and cache", svcs)
+	}
+
+	if len(svcs[1].Ports) != 1 || 6379 != svcs[1].Ports[0].HostPort {
+		t.Fatal("cache ports", "6379", svcs[1].Ports)
+	}
+
+	mounts := svcs[1].Mounts
+	expected := []container.Mount{
+		{
+			Type:        container.MountBind,
+			Source:      filepath.Join(pwd, "redis.conf"),
+			Destination: "/etc/redis.conf",
+			ReadOnly:    true,
+		},
+		{Type: container.MountVolume, Destination: "/data"},
+	}
+	if len(mounts) != 2 || expected[0] != mounts[0] || expected[1] != mounts[1] {
+		t.Fatal("cache mounts", expected, mounts)
+	}
+
+	if len(svcs[1].DependsOn) != 1 || "db" != svcs[1].DependsOn[0] {
+		t.Fatal("cache dependencies", []string{"db"}, svcs[1].DependsOn)
+	}
+
+	if len(svcs[0].Envs) != 1 || "POSTGRES_PASSWORD=secret" != svcs[0].Envs[0] {
+		t.Fatal("db envs", "POSTGRES_PASSWORD=secret", svcs[0].Envs)
+	}
+
+	hc := svcs[0].Healthcheck
+	if hc == nil || 2*time.Second != hc.Interval || "pg_isready" != hc.Test[1] {
+		t.Fatal("db healthcheck", "pg_isready every 2s", hc)
+	}
+}
+
+func TestCreateWithNetwork(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/foo/sh",
+			Network: config.Network{
+				Hostname: "app",
+				Aliases:  []string{"api"},
+				External: []string{"compose_default"},
+			},
+		},
+	}
+
+	ctl := newMockCtl(nil)
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newCreateCmd(ctl, s, cfg)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
 	}
 
-	if tcp[0] != 99999 {
-		t.Fatal("saving ports", 99999, ok)
+	m := s.env().Container
+	if "app" != m.Hostname {
+		t.Fatal("hostname", "app", m.Hostname)
 	}
 
-	udp, ok := s.env.Container.Ports["udp"]
-	if !ok {
-		t.Fatal("saving ports", true, ok)
+	if len(m.Aliases) != 1 || "api" != m.Aliases[0] {
+		t.Fatal("aliases", []string{"api"}, m.Aliases)
 	}
 
-	if udp[0] != 88888 {
-		t.Fatal("saving ports", 88888, ok)
+	if len(m.ExternalNetworks) != 1 || "compose_default" != m.ExternalNetworks[0] {
+		t.Fatal("external networks", []string{"compose_default"}, m.ExternalNetworks)
 	}
 }
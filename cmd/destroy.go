@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/UltimateSoftware/envctl/internal/db"
 	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/spf13/cobra"
 )
 
@@ -19,7 +19,7 @@ func newDestroyCmd(ctl container.Controller, s db.Store) *cobra.Command {
 To create it, run "envctl create".`
 
 	runDestroy := func(cmd *cobra.Command, args []string) {
-		env, err := s.Read()
+		env, err := s.Read(envName)
 		if err != nil {
 			fmt.Printf("error reading data store: %v\n", err)
 			os.Exit(1)
@@ -27,7 +27,7 @@ To create it, run "envctl create".`
 
 		if !env.Initialized() {
 			fmt.Println(msgEnvOff)
-			s.Delete()
+			s.Delete(envName)
 			os.Exit(1)
 		}
 
@@ -38,7 +38,7 @@ To create it, run "envctl create".`
 			os.Exit(1)
 		}
 
-		if err := s.Delete(); err != nil {
+		if err := s.Delete(envName); err != nil {
 			fmt.Printf("error deleting data store: %v\n", err)
 			os.Exit(1)
 		}
@@ -3,8 +3,10 @@ package cmd
 import (
 	"testing"
 
-	"github.com/UltimateSoftware/envctl/internal/db"
+	"github.com/UltimateSoftware/envctl/internal/config"
 	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/UltimateSoftware/envctl/test_pkg"
 )
 
@@ -22,12 +24,10 @@ func TestDestroy(got *testing.T) {
 		},
 	}
 
-	s := &memStore{
-		env: db.Environment{
-			Status:    db.StatusReady,
-			Container: cnt,
-		},
-	}
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
 
 	ctl := newMockCtl(&cnt)
 
@@ -49,7 +49,45 @@ func TestDestroy(got *testing.T) {
 		t.Fatal("backing container", nil, ctl.current)
 	}
 
-	if db.StatusOff != s.env.Status {
-		t.Fatal("status", db.StatusOff, s.env.Status)
+	if db.StatusOff != s.env().Status {
+		t.Fatal("status", db.StatusOff, s.env().Status)
+	}
+}
+
+func TestDestroyMissingContainer(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+			Services: map[string]config.Service{
+				"db": {Image: "postgres:11"},
+			},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	env, _ := s.Read(envName)
+
+	// Only the environment's container was removed outside of envctl, which
+	// "status" reports, telling the user to destroy the environment.
+	ctl.Delete(env.Container.ID)
+
+	runCmd(t, newDestroyCmd(ctl, s))
+
+	if ctl.Containers() != 0 {
+		t.Fatal("containers after destroy", nil, ctl.Containers())
+	}
+
+	if ctl.HasImage(env.Container.ImageID) || ctl.HasNetwork(env.Container.Network) {
+		t.Fatal("image and network after destroy", "removed", env.Container)
+	}
+
+	if env, _ := s.Read(envName); db.StatusOff != env.Status {
+		t.Fatal("status", db.StatusOff, env.Status)
 	}
 }
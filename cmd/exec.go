@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/docker/docker/pkg/term"
+	"github.com/spf13/cobra"
+)
+
+func newExecCmd(ctl container.Controller, s db.Store) *cobra.Command {
+	execDesc := "run a single command in the current environment"
+
+	execLongDesc := `exec - Run a single command in the current environment
+
+"exec" will run the given command inside the current environment without
+opening an interactive shell, and exit with the command's exit code.
+
+If stdin isn't a terminal, it will be forwarded to the command. Flags after
+the command are passed through to it:
+
+  envctl exec make test
+  envctl exec bundle exec rspec --fail-fast`
+
+	msgEnvOff := `Wait! The environment isn't ready yet!
+
+To get it ready, run "envctl create".
+`
+
+	msgEnvStopped := `The environment is stopped!
+
+To start it again, run "envctl start".
+`
+
+	runExec := func(cmd *cobra.Command, args []string) {
+		env, err := s.Read(envName)
+		if err != nil {
+			fmt.Printf("error reading data store: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		if !env.Initialized() {
+			fmt.Print(msgEnvOff)
+			osExit(1)
+			return
+		}
+
+		if env.Status == db.StatusStopped {
+			fmt.Print(msgEnvStopped)
+			osExit(1)
+			return
+		}
+
+		// Only hand the command a TTY when there's a terminal on the other end,
+		// so that output piped into other tools keeps stdout and stderr apart.
+		opts := container.RunOpts{
+			TTY:   term.IsTerminal(os.Stdin.Fd()),
+			Stdin: true,
+		}
+
+		if err := ctl.Run(env.Container, args, opts); err != nil {
+			if exitErr, ok := err.(*container.ExitError); ok {
+				osExit(exitErr.Code)
+				return
+			}
+
+			fmt.Printf("error running %v: %v\n", args, err)
+			osExit(1)
+			return
+		}
+	}
+
+	execCmd := &cobra.Command{
+		Use:   "exec COMMAND [ARG...]",
+		Short: execDesc,
+		Long:  execLongDesc,
+		Args:  cobra.MinimumNArgs(1),
+		Run:   runExec,
+	}
+
+	// Everything after the command belongs to the command, not to envctl.
+	execCmd.Flags().SetInterspersed(false)
+
+	return execCmd
+}
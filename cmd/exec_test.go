@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestExec(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cnt := container.Metadata{
+		ID:        "foocnt",
+		ImageID:   "fooimg",
+		BaseName:  "fooenv",
+		BaseImage: "scratch",
+		Shell:     "/foo/sh",
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	ctl := newMockCtl(&cnt)
+
+	var ranOn container.Metadata
+	var ran []string
+	var ranWith container.RunOpts
+	ctl.runFn = func(
+		m container.Metadata,
+		cmds []string,
+		opts container.RunOpts,
+	) error {
+		ranOn = m
+		ran = cmds
+		ranWith = opts
+		return nil
+	}
+
+	cmd := newExecCmd(ctl, s)
+
+	expected := []string{"make", "test", "--verbose"}
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, expected)
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if ranOn.ID != cnt.ID {
+		t.Fatal("container", cnt.ID, ranOn.ID)
+	}
+
+	// Whether or not there's a TTY depends on where the tests are run from,
+	// but stdin should always be forwarded.
+	if !ranWith.Stdin {
+		t.Fatal("forwarding stdin", true, ranWith.Stdin)
+	}
+
+	if len(ran) != len(expected) {
+		t.Fatal("command", expected, ran)
+	}
+
+	for i := range expected {
+		if expected[i] != ran[i] {
+			t.Fatal("command", expected, ran)
+		}
+	}
+}
+
+func TestExecExitCode(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	ctl.Script("make test", fake.Result{ExitCode: 3})
+
+	runCmd(t, newExecCmd(ctl, s), "make", "test")
+
+	// The command's own exit code is passed on, rather than a generic 1.
+	if 3 != exitCode {
+		t.Fatal("exit code", 3, exitCode)
+	}
+
+	exitCode = 0
+	runCmd(t, newExecCmd(ctl, s), "make", "lint")
+
+	if 0 != exitCode {
+		t.Fatal("exit code of a passing command", 0, exitCode)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	"github.com/spf13/cobra"
+)
+
+// TestLifecycle runs an environment through its whole life on the fake
+// runtime, checking the store and the container after every command.
+func TestLifecycle(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:     "alpine",
+			Shell:     "/bin/sh",
+			Mount:     "/mnt/repo",
+			Bootstrap: []string{"make deps"},
+		},
+	}
+
+	steps := []struct {
+		cmd      *cobra.Command
+		status   int
+		expected container.Status
+	}{
+		{newCreateCmd(ctl, s, cfg), db.StatusReady, container.StatusRunning},
+		{newStopCmd(ctl, s), db.StatusStopped, container.StatusExited},
+		{newStartCmd(ctl, s), db.StatusReady, container.StatusRunning},
+		{newStatusCmd(ctl, s, memConfig{}), db.StatusReady, container.StatusRunning},
+		{newDestroyCmd(ctl, s), db.StatusOff, container.StatusMissing},
+	}
+
+	var m container.Metadata
+
+	for _, step := range steps {
+		outch, errch := test_pkg.HijackStdout(func() {
+			step.cmd.Run(step.cmd, []string{})
+		})
+
+		select {
+		case err := <-errch:
+			t.Fatal("hijacking output", nil, err)
+		case <-outch:
+		}
+
+		env, _ := s.Read(envName)
+		if step.status != env.Status {
+			t.Fatal(step.cmd.Use+" status", step.status, env.Status)
+		}
+
+		if env.Container.ID != "" {
+			m = env.Container
+		}
+
+		state, _ := ctl.Inspect(m)
+		if step.expected != state.Status {
+			t.Fatal(step.cmd.Use+" container status", step.expected, state.Status)
+		}
+	}
+
+	if 1 != len(ctl.Execs()) {
+		t.Fatal("bootstrap runs", 1, len(ctl.Execs()))
+	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+}
+
+func TestLoginAfterExit(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+	runCmd(t, newLoginCmd(ctl, s, cfg))
+
+	env, _ := s.Read(envName)
+
+	// Logging out exits the shell, which is the container's main process.
+	if err := ctl.Exit(env.Container.ID, 0); err != nil {
+		t.Fatal("exiting container", nil, err)
+	}
+
+	out := runCmd(t, newLoginCmd(ctl, s, cfg))
+
+	cnt, _ := ctl.Container(env.Container.ID)
+	if 2 != cnt.Attached || 0 != exitCode {
+		t.Fatal("logins", 2, out)
+	}
+
+	if env, _ := s.Read(envName); db.StatusReady != env.Status {
+		t.Fatal("status after logging in again", db.StatusReady, env.Status)
+	}
+
+	if container.StatusRunning != cnt.State.Status {
+		t.Fatal("container status", container.StatusRunning, cnt.State.Status)
+	}
+}
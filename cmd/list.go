@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/spf13/cobra"
+)
+
+func newListCmd(s db.Store) *cobra.Command {
+	listDesc := "list the environments for the current config file"
+
+	listLongDesc := `list - List the environments for the current config file
+
+"list" shows every environment that's been created for the current config file
+along with its status. The environment currently selected with "--env" is
+marked with a "*".`
+
+	msgNoEnvs := `There aren't any environments yet.
+
+Run "envctl create" to spin one up!`
+
+	runList := func(cmd *cobra.Command, args []string) {
+		envs, err := s.List()
+		if err != nil {
+			fmt.Printf("error reading data store: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(envs) == 0 {
+			fmt.Println(msgNoEnvs)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tSTATUS")
+		for _, env := range envs {
+			current := ""
+			if env.Name == envName {
+				current = "*"
+			}
+
+			fmt.Fprintf(w, "%v\t%v\t%v\n", current, env.Name,
+				db.StatusName(env.Status))
+		}
+		w.Flush()
+	}
+
+	return &cobra.Command{
+		Use:   "list",
+		Short: listDesc,
+		Long:  listLongDesc,
+		Run:   runList,
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestList(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusReady,
+	})
+	s.Create("ci", db.Environment{
+		Status: db.StatusError,
+	})
+
+	cmd := newListCmd(s)
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := `   NAME     STATUS
+   ci       error
+*  default  ready
+`
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+}
+
+func TestListEmpty(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	s := &memStore{db.NewMemStore()}
+
+	cmd := newListCmd(s)
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := `There aren't any environments yet.
+
+Run "envctl create" to spin one up!
+`
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+}
@@ -4,26 +4,53 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/UltimateSoftware/envctl/internal/db"
+	"github.com/UltimateSoftware/envctl/internal/config"
 	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/spf13/cobra"
 )
 
-func newLoginCmd(ctl container.Controller, s db.Store) *cobra.Command {
+func newLoginCmd(
+	ctl container.Controller,
+	s db.Store,
+	l config.Loader,
+) *cobra.Command {
+	var force bool
+
 	loginDesc := "log in to the current environment"
 
 	loginLongDesc := `login - Log in to the current environment
 
 "login" will log in to the current environment using the shell specified in
-the config file.`
+the config file.
+
+If the config file has changed since the environment was created, "login"
+refuses to log in to it, since it's not what the config file describes
+anymore. Recreate it with "envctl rebuild", or log in anyway with "--force".`
 
 	msgEnvOff := `Wait! The environment isn't ready yet!
 
 To get it ready, run "envctl create".
+`
+
+	msgEnvStopped := `The environment is stopped!
+
+To start it again, run "envctl start".
+`
+
+	msgConfigChanged := `The config file has changed since the environment was created!
+
+To recreate the environment from it, run "envctl rebuild", or log in anyway
+with "envctl login --force".
+`
+
+	msgEnvError := `Something is wrong with the environment. :(
+
+Try recreating it by running "envctl destroy", followed by "envctl create".
 `
 
 	runLogin := func(cmd *cobra.Command, args []string) {
-		env, err := s.Read()
+		env, err := s.Read(envName)
 		if err != nil {
 			fmt.Printf("error reading data store: %v\n", err)
 			os.Exit(1)
@@ -34,16 +61,65 @@ To get it ready, run "envctl create".
 			os.Exit(1)
 		}
 
+		if env.Status == db.StatusStopped {
+			fmt.Print(msgEnvStopped)
+			os.Exit(1)
+		}
+
+		env, drift, err := reconcile(ctl, env)
+		if err != nil {
+			fmt.Printf("error inspecting environment: %v\n", err)
+			os.Exit(1)
+		}
+
+		// An environment whose container or image has gone missing can't be
+		// logged in to, but one that's only left in "error" state by a failed
+		// bootstrap still can, to debug it.
+		if drift != "" {
+			s.Create(envName, env)
+
+			if env.Status == db.StatusError {
+				fmt.Printf("%v\n\n%v", drift, msgEnvError)
+				os.Exit(1)
+			}
+
+		}
+
+		if !force {
+			changed, err := configChanged(l, env)
+			if err != nil {
+				fmt.Printf("error checking config file for changes: %v\n", err)
+				osExit(1)
+				return
+			}
+
+			if changed {
+				fmt.Print(msgConfigChanged)
+				osExit(1)
+				return
+			}
+		}
+
 		if err := ctl.Attach(env.Container); err != nil {
 			fmt.Printf("error logging in to environment: %v\n", err)
 			os.Exit(1)
 		}
 	}
 
-	return &cobra.Command{
+	loginCmd := &cobra.Command{
 		Use:   "login",
 		Short: loginDesc,
 		Long:  loginLongDesc,
 		Run:   runLogin,
 	}
+
+	loginCmd.Flags().BoolVarP(
+		&force,
+		"force",
+		"f",
+		false,
+		"log in even if the config file has changed",
+	)
+
+	return loginCmd
 }
@@ -2,28 +2,28 @@ package cmd
 
 import (
 	"github.com/UltimateSoftware/envctl/internal/config"
-	"github.com/UltimateSoftware/envctl/internal/db"
 	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/google/uuid"
 )
 
 type memStore struct {
-	env db.Environment
+	*db.MemStore
 }
 
-func (s *memStore) Create(e db.Environment) error {
-	s.env = e
+// newMemStore returns a memStore holding e as the environment currently
+// selected by envName.
+func newMemStore(e db.Environment) *memStore {
+	s := &memStore{db.NewMemStore()}
+	s.Create(envName, e)
 
-	return nil
+	return s
 }
 
-func (s *memStore) Read() (db.Environment, error) {
-	return s.env, nil
-}
-
-func (s *memStore) Delete() error {
-	s.env = db.Environment{}
-	return nil
+// env returns the environment currently selected by envName.
+func (s *memStore) env() db.Environment {
+	e, _ := s.Read(envName)
+	return e
 }
 
 type mockCtl struct {
@@ -31,10 +31,13 @@ type mockCtl struct {
 
 	// These allow the specific tests to override the underlying behavior if
 	// necessary to test alternative code-paths.
-	createFn func(container.Metadata) (container.Metadata, error)
-	removeFn func(container.Metadata) error
-	attachFn func(container.Metadata) error
-	runFn    func(container.Metadata, []string) error
+	createFn  func(container.Metadata) (container.Metadata, error)
+	removeFn  func(container.Metadata) error
+	startFn   func(container.Metadata) error
+	stopFn    func(container.Metadata) error
+	attachFn  func(container.Metadata) error
+	runFn     func(container.Metadata, []string, container.RunOpts) error
+	inspectFn func(container.Metadata) (container.State, error)
 }
 
 func newMockCtl(init *container.Metadata) *mockCtl {
@@ -62,14 +65,34 @@ func newMockCtl(init *container.Metadata) *mockCtl {
 		return nil
 	}
 
+	ctl.startFn = func(m container.Metadata) error {
+		return nil
+	}
+
+	ctl.stopFn = func(m container.Metadata) error {
+		return nil
+	}
+
 	ctl.attachFn = func(m container.Metadata) error {
 		return nil
 	}
 
-	ctl.runFn = func(m container.Metadata, cmds []string) error {
+	ctl.runFn = func(
+		m container.Metadata,
+		cmds []string,
+		opts container.RunOpts,
+	) error {
 		return nil
 	}
 
+	ctl.inspectFn = func(m container.Metadata) (container.State, error) {
+		if ctl.current == nil || ctl.current.ID != m.ID {
+			return container.State{Status: container.StatusMissing}, nil
+		}
+
+		return container.State{Status: container.StatusRunning}, nil
+	}
+
 	return ctl
 }
 
@@ -81,12 +104,28 @@ func (ctl *mockCtl) Remove(m container.Metadata) error {
 	return ctl.removeFn(m)
 }
 
+func (ctl *mockCtl) Start(m container.Metadata) error {
+	return ctl.startFn(m)
+}
+
+func (ctl *mockCtl) Stop(m container.Metadata) error {
+	return ctl.stopFn(m)
+}
+
 func (ctl *mockCtl) Attach(m container.Metadata) error {
 	return ctl.attachFn(m)
 }
 
-func (ctl *mockCtl) Run(m container.Metadata, cmds []string) error {
-	return ctl.runFn(m, cmds)
+func (ctl *mockCtl) Run(
+	m container.Metadata,
+	cmds []string,
+	opts container.RunOpts,
+) error {
+	return ctl.runFn(m, cmds, opts)
+}
+
+func (ctl *mockCtl) Inspect(m container.Metadata) (container.State, error) {
+	return ctl.inspectFn(m)
 }
 
 type memConfig struct {
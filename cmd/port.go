@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/spf13/cobra"
+)
+
+func newPortCmd(ctl container.Controller, s db.Store) *cobra.Command {
+	portDesc := "show where the current environment's ports are published"
+
+	portLongDesc := `port - Show where the current environment's ports are published
+
+"port" asks the container engine which host ports the environment's ports were
+published on, including the ones it picked for ports with an "auto" host port.
+Given a container port, like "3000" or "53/udp", it only shows that one:
+
+  envctl port
+  envctl port 3000`
+
+	msgEnvOff := `Wait! The environment isn't ready yet!
+
+To get it ready, run "envctl create".
+`
+
+	msgEnvNotRunning := `The environment isn't running, so its ports aren't published!
+
+To start it, run "envctl start".
+`
+
+	runPort := func(cmd *cobra.Command, args []string) {
+		var want *container.PortBinding
+		if len(args) > 0 {
+			p, err := parseContainerPort(args[0])
+			if err != nil {
+				fmt.Printf("error: %v\n", err)
+				osExit(1)
+				return
+			}
+
+			want = &p
+		}
+
+		env, err := s.Read(envName)
+		if err != nil {
+			fmt.Printf("error reading data store: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		if !env.Initialized() {
+			fmt.Print(msgEnvOff)
+			osExit(1)
+			return
+		}
+
+		state, err := ctl.Inspect(env.Container)
+		if err != nil {
+			fmt.Printf("error inspecting environment: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		if state.Status != container.StatusRunning {
+			fmt.Print(msgEnvNotRunning)
+			osExit(1)
+			return
+		}
+
+		found := false
+		for _, p := range env.Container.Ports {
+			if want != nil && (want.ContainerPort != p.ContainerPort ||
+				want.Protocol != p.Protocol) {
+
+				continue
+			}
+
+			for _, b := range effectiveBindings(p, state.Ports) {
+				found = true
+
+				ip := b.HostIP
+				if ip == "" {
+					ip = "0.0.0.0"
+				}
+
+				fmt.Printf("%v/%v -> %v\n", p.ContainerPort, p.Protocol,
+					net.JoinHostPort(ip, strconv.Itoa(b.HostPort)))
+			}
+		}
+
+		if want != nil && !found {
+			fmt.Printf("error: port %v/%v isn't published\n", want.ContainerPort,
+				want.Protocol)
+			osExit(1)
+		}
+	}
+
+	return &cobra.Command{
+		Use:   "port [CONTAINER_PORT[/PROTOCOL]]",
+		Short: portDesc,
+		Long:  portLongDesc,
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runPort,
+	}
+}
+
+// parseContainerPort parses a port like "3000" or "53/udp". The protocol
+// defaults to tcp.
+func parseContainerPort(raw string) (container.PortBinding, error) {
+	port, proto := raw, "tcp"
+	if i := strings.Index(raw, "/"); i >= 0 {
+		port, proto = raw[:i], raw[i+1:]
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return container.PortBinding{}, fmt.Errorf("invalid port %q", raw)
+	}
+
+	return container.PortBinding{ContainerPort: p, Protocol: proto}, nil
+}
+
+// effectiveBindings returns the bindings the container engine published for
+// the port p asked for. Ports that were asked for with a host port or IP
+// only match bindings with the same one.
+func effectiveBindings(
+	p container.PortBinding,
+	published []container.PortBinding,
+) []container.PortBinding {
+	bindings := []container.PortBinding{}
+
+	for _, b := range published {
+		switch {
+		case b.ContainerPort != p.ContainerPort || b.Protocol != p.Protocol:
+		case p.HostPort != 0 && b.HostPort != p.HostPort:
+		case p.HostIP != "" && b.HostIP != p.HostIP:
+		default:
+			bindings = append(bindings, b)
+		}
+	}
+
+	return bindings
+}
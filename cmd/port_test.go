@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestPort(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+			Ports: config.Ports{"127.0.0.1:8080:80", "auto:3000", "53/udp"},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	runCmd(t, newPortCmd(ctl, s))
+	if 1 != exitCode {
+		t.Fatal("exit code before starting", 1, exitCode)
+	}
+	exitCode = 0
+
+	env, _ := s.Read(envName)
+	ctl.Start(env.Container)
+
+	tests := []struct {
+		args     []string
+		expected string
+		code     int
+	}{
+		{
+			args: []string{},
+			expected: "80/tcp -> 127.0.0.1:8080\n" +
+				"3000/tcp -> 0.0.0.0:32769\n" +
+				"53/udp -> 0.0.0.0:53\n",
+		},
+		{args: []string{"3000"}, expected: "3000/tcp -> 0.0.0.0:32769\n"},
+		{args: []string{"53/udp"}, expected: "53/udp -> 0.0.0.0:53\n"},
+		{
+			args:     []string{"53"},
+			expected: "error: port 53/tcp isn't published\n",
+			code:     1,
+		},
+		{
+			args:     []string{"web"},
+			expected: "error: invalid port \"web\"\n",
+			code:     1,
+		},
+	}
+
+	for _, test := range tests {
+		exitCode = 0
+
+		out := runCmd(t, newPortCmd(ctl, s), test.args...)
+		if test.expected != out {
+			t.Fatal("output of port", test.expected, out)
+		}
+
+		if test.code != exitCode {
+			t.Fatal("exit code of port", test.code, exitCode)
+		}
+	}
+}
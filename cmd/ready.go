@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// probeTimeout is how long a single attempt of a port or URL probe gets to
+// connect and respond.
+var probeTimeout = 5 * time.Second
+
+// waitReady runs the environment's ready probe until it passes, or until its
+// timeout is up, in which case it returns why the last attempt failed.
+func waitReady(ctl container.Controller, m container.Metadata) error {
+	p := m.Ready
+	deadline := time.Now().Add(p.Timeout)
+
+	for {
+		state, err := ctl.Inspect(m)
+		if err == nil {
+			err = probeReady(ctl, m, state)
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("not ready after %v: %v", p.Timeout, err)
+		}
+
+		time.Sleep(p.Interval)
+	}
+}
+
+// probeReady runs the environment's ready probe once, and returns why it
+// failed, if it did. Port and URL probes find the published port in state,
+// which is what the container engine says about the environment's container.
+func probeReady(
+	ctl container.Controller,
+	m container.Metadata,
+	state container.State,
+) error {
+	p := m.Ready
+
+	if p.Command != "" {
+		// The command's output is only noise while it's run over and over.
+		cmd := fmt.Sprintf("(%v) >/dev/null 2>&1", p.Command)
+
+		err := ctl.Run(m, []string{m.Shell, "-c", cmd}, container.RunOpts{})
+		if exitErr, ok := err.(*container.ExitError); ok {
+			return fmt.Errorf("%q exited with status %v", p.Command, exitErr.Code)
+		}
+
+		return err
+	}
+
+	if p.Port != 0 {
+		addr, err := publishedAddr(state, p.Port)
+		if err != nil {
+			return err
+		}
+
+		conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+		if err != nil {
+			return fmt.Errorf("nothing is listening on port %v", p.Port)
+		}
+
+		return conn.Close()
+	}
+
+	port, err := config.URLPort(p.URL)
+	if err != nil {
+		return err
+	}
+
+	addr, err := publishedAddr(state, port)
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return err
+	}
+
+	u.Host = addr
+
+	client := http.Client{Timeout: probeTimeout}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return fmt.Errorf("error requesting %v: %v", p.URL, err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%v responded with %v", p.URL, resp.Status)
+	}
+
+	return nil
+}
+
+// publishedAddr returns the address on the host that a TCP port of the
+// environment's container, in the given state, is published on. Ports
+// published on every address are reached through the loopback address.
+func publishedAddr(state container.State, port int) (string, error) {
+	if state.Status != container.StatusRunning {
+		return "", fmt.Errorf("the environment is %v", state.Status)
+	}
+
+	for _, b := range state.Ports {
+		if b.ContainerPort != port || b.Protocol != "tcp" || b.HostPort == 0 {
+			continue
+		}
+
+		host := b.HostIP
+		switch host {
+		case "", "0.0.0.0":
+			host = "127.0.0.1"
+		case "::":
+			host = "::1"
+		}
+
+		return net.JoinHostPort(host, strconv.Itoa(b.HostPort)), nil
+	}
+
+	return "", fmt.Errorf("port %v isn't published", port)
+}
@@ -0,0 +1,408 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+// publishOn makes ctl report the container's port as published on the host
+// address of l.
+func publishOn(ctl *mockCtl, port int, l net.Addr) {
+	hostPort := l.(*net.TCPAddr).Port
+
+	ctl.inspectFn = func(m container.Metadata) (container.State, error) {
+		return container.State{
+			Status: container.StatusRunning,
+			Ports: []container.PortBinding{
+				{ContainerPort: port, HostPort: hostPort, Protocol: "tcp"},
+			},
+		}, nil
+	}
+}
+
+// inspect returns the state ctl reports for the container of m.
+func inspect(ctl container.Controller, m container.Metadata) container.State {
+	state, _ := ctl.Inspect(m)
+	return state
+}
+
+func TestProbeReadyCommand(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	var ran []string
+	code := 1
+
+	ctl := newMockCtl(nil)
+	ctl.runFn = func(m container.Metadata, cmd []string, opts container.RunOpts) error {
+		ran = cmd
+		if code != 0 {
+			return &container.ExitError{Cmd: cmd, Code: code}
+		}
+
+		return nil
+	}
+
+	m := container.Metadata{
+		Shell: "/bin/sh",
+		Ready: &container.Probe{Command: "curl -f localhost"},
+	}
+
+	err := probeReady(ctl, m, inspect(ctl, m))
+	expected := `"curl -f localhost" exited with status 1`
+	if err == nil || expected != err.Error() {
+		t.Fatal("probeReady() error", expected, err)
+	}
+
+	if len(ran) != 3 || "/bin/sh" != ran[0] || !strings.Contains(ran[2], "curl -f localhost") {
+		t.Fatal("probe command", "curl -f localhost with /bin/sh", ran)
+	}
+
+	code = 0
+	if err := probeReady(ctl, m, inspect(ctl, m)); err != nil {
+		t.Fatal("probeReady()", nil, err)
+	}
+}
+
+func TestProbeReadyPort(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listening", nil, err)
+	}
+
+	ctl := newMockCtl(nil)
+	publishOn(ctl, 3000, l.Addr())
+
+	m := container.Metadata{Ready: &container.Probe{Port: 3000}}
+	if err := probeReady(ctl, m, inspect(ctl, m)); err != nil {
+		t.Fatal("probeReady()", nil, err)
+	}
+
+	l.Close()
+
+	err = probeReady(ctl, m, inspect(ctl, m))
+	expected := "nothing is listening on port 3000"
+	if err == nil || expected != err.Error() {
+		t.Fatal("probeReady() error", expected, err)
+	}
+
+	m.Ready.Port = 4000
+
+	err = probeReady(ctl, m, inspect(ctl, m))
+	expected = "port 4000 isn't published"
+	if err == nil || expected != err.Error() {
+		t.Fatal("probeReady() error", expected, err)
+	}
+}
+
+func TestProbeReadyURL(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	status := http.StatusServiceUnavailable
+	path := ""
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			w.WriteHeader(status)
+		},
+	))
+	defer srv.Close()
+
+	ctl := newMockCtl(nil)
+	publishOn(ctl, 3000, srv.Listener.Addr())
+
+	m := container.Metadata{
+		Ready: &container.Probe{URL: "http://localhost:3000/health"},
+	}
+
+	err := probeReady(ctl, m, inspect(ctl, m))
+	expected := "http://localhost:3000/health responded with 503 Service Unavailable"
+	if err == nil || expected != err.Error() {
+		t.Fatal("probeReady() error", expected, err)
+	}
+
+	if "/health" != path {
+		t.Fatal("requested path", "/health", path)
+	}
+
+	status = http.StatusOK
+	if err := probeReady(ctl, m, inspect(ctl, m)); err != nil {
+		t.Fatal("probeReady()", nil, err)
+	}
+}
+
+func TestCreateWaitsForReady(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	attempts := 0
+
+	ctl := newMockCtl(nil)
+	ctl.runFn = func(m container.Metadata, cmd []string, opts container.RunOpts) error {
+		attempts++
+		if attempts < 3 {
+			return &container.ExitError{Cmd: cmd, Code: 1}
+		}
+
+		return nil
+	}
+
+	s := newMemStore(db.Environment{Status: db.StatusOff})
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/bin/sh",
+			Ready: &config.Ready{Command: "test -f /tmp/up", Interval: "10ms"},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	if 3 != attempts {
+		t.Fatal("probe attempts", 3, attempts)
+	}
+
+	if db.StatusReady != s.env().Status {
+		t.Fatal("status", db.StatusReady, s.env().Status)
+	}
+
+	ready := s.env().Container.Ready
+	if ready == nil || 10*time.Millisecond != ready.Interval || 2*time.Minute != ready.Timeout {
+		t.Fatal("saved probe", cfg.opts.Ready, ready)
+	}
+}
+
+func TestCreateStartsForReady(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listening", nil, err)
+	}
+	defer l.Close()
+
+	hostPort := l.Addr().(*net.TCPAddr).Port
+
+	// The fake controller leaves the container created until it's started,
+	// like Docker, and there's no bootstrap to start it.
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+			Ports: config.Ports{fmt.Sprintf("127.0.0.1:%v:3000", hostPort)},
+			Ready: &config.Ready{Port: 3000, Timeout: "1s", Interval: "10ms"},
+		},
+	}
+
+	out := runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	env, _ := s.Read(envName)
+	if 0 != exitCode || db.StatusReady != env.Status {
+		t.Fatal("create with a port probe", "a ready environment", out)
+	}
+
+	if state, _ := ctl.Inspect(env.Container); container.StatusRunning != state.Status {
+		t.Fatal("container status", container.StatusRunning, state.Status)
+	}
+}
+
+func TestCreateNotReady(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := newMockCtl(nil)
+	ctl.runFn = func(m container.Metadata, cmd []string, opts container.RunOpts) error {
+		return &container.ExitError{Cmd: cmd, Code: 7}
+	}
+
+	s := newMemStore(db.Environment{Status: db.StatusOff})
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "test",
+			Shell: "/bin/sh",
+			Ready: &config.Ready{
+				Command:  "test -f /tmp/up",
+				Timeout:  "50ms",
+				Interval: "10ms",
+			},
+		},
+	}
+
+	out := runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	expected := `environment isn't ready: not ready after 50ms: "test -f /tmp/up" exited with status 7`
+	if !strings.Contains(out, expected) {
+		t.Fatal("create output", expected, out)
+	}
+
+	if 1 != exitCode {
+		t.Fatal("exit code", 1, exitCode)
+	}
+
+	if db.StatusError != s.env().Status {
+		t.Fatal("status", db.StatusError, s.env().Status)
+	}
+}
+
+func TestStatusReadyProbe(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cnt := container.Metadata{
+		ID:      "foocnt",
+		ImageID: "fooimg",
+		Shell:   "/bin/sh",
+		Ready:   &container.Probe{Command: "test -f /tmp/up"},
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	ctl := newMockCtl(&cnt)
+	ctl.runFn = func(m container.Metadata, cmd []string, opts container.RunOpts) error {
+		return &container.ExitError{Cmd: cmd, Code: 1}
+	}
+
+	out := runCmd(t, newStatusCmd(ctl, s, memConfig{}))
+
+	expected := `command "test -f /tmp/up": failing, "test -f /tmp/up" exited with status 1`
+	if !strings.Contains(out, expected) {
+		t.Fatal("status output", expected, out)
+	}
+
+	ctl.runFn = func(m container.Metadata, cmd []string, opts container.RunOpts) error {
+		return nil
+	}
+
+	cmd := newStatusCmd(ctl, s, memConfig{})
+	cmd.Flags().Set("output", "json")
+
+	var actual statusOutput
+	if err := json.Unmarshal([]byte(runCmd(t, cmd)), &actual); err != nil {
+		t.Fatal("decoding output", nil, err)
+	}
+
+	expectedReady := statusReady{Probe: `command "test -f /tmp/up"`, Passing: true}
+	if actual.Ready == nil || expectedReady != *actual.Ready {
+		t.Fatal("ready probe", expectedReady, actual.Ready)
+	}
+}
+
+func TestStatusReadyProbeStopped(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cnt := container.Metadata{
+		ID:      "foocnt",
+		ImageID: "fooimg",
+		Shell:   "/bin/sh",
+		Ready:   &container.Probe{Command: "test -f /tmp/up"},
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusStopped,
+		Container: cnt,
+	})
+
+	ran := false
+
+	ctl := newMockCtl(&cnt)
+	ctl.inspectFn = func(m container.Metadata) (container.State, error) {
+		return container.State{Status: container.StatusExited}, nil
+	}
+	ctl.runFn = func(m container.Metadata, cmd []string, opts container.RunOpts) error {
+		ran = true
+		return nil
+	}
+
+	cmd := newStatusCmd(ctl, s, memConfig{})
+	cmd.Flags().Set("output", "json")
+
+	var actual statusOutput
+	if err := json.Unmarshal([]byte(runCmd(t, cmd)), &actual); err != nil {
+		t.Fatal("decoding output", nil, err)
+	}
+
+	// Running the probe would have started the environment.
+	if ran {
+		t.Fatal("probe run on a stopped environment", false, ran)
+	}
+
+	expected := statusReady{Probe: `command "test -f /tmp/up"`, Error: "not running"}
+	if actual.Ready == nil || expected != *actual.Ready {
+		t.Fatal("ready probe", expected, actual.Ready)
+	}
+}
+
+func TestStatusInspectsOnce(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listening", nil, err)
+	}
+	defer l.Close()
+
+	cnt := container.Metadata{
+		ID:      "foocnt",
+		ImageID: "fooimg",
+		Ready:   &container.Probe{Port: 3000},
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	ctl := newMockCtl(&cnt)
+	publishOn(ctl, 3000, l.Addr())
+
+	inspects := 0
+	inspectFn := ctl.inspectFn
+	ctl.inspectFn = func(m container.Metadata) (container.State, error) {
+		inspects++
+		return inspectFn(m)
+	}
+
+	out := runCmd(t, newStatusCmd(ctl, s, memConfig{}))
+
+	if !strings.Contains(out, "port 3000: passing") {
+		t.Fatal("status output", "port 3000: passing", out)
+	}
+
+	if 1 != inspects {
+		t.Fatal("inspections", 1, inspects)
+	}
+}
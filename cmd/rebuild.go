@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/spf13/cobra"
+)
+
+func newRebuildCmd(
+	ctl container.Controller,
+	s db.Store,
+	l config.Loader,
+) *cobra.Command {
+	rebuildDesc := "destroy and re-create the current environment"
+
+	rebuildLongDesc := `rebuild - Destroy and re-create the current environment
+
+"rebuild" destroys the current environment, if there is one, and creates it
+again from the config file, like "envctl destroy" followed by "envctl create".
+It's how to bring an environment up to date once the config file has changed.
+
+Caches are kept, so the new environment picks up where the old one left off,
+and so are the named volumes of its services. Anything else changed inside of
+the environment is lost.`
+
+	createCmd := newCreateCmd(ctl, s, l)
+
+	runRebuild := func(cmd *cobra.Command, args []string) {
+		env, err := s.Read(envName)
+		if err != nil {
+			fmt.Printf("error reading data store: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		// A config file that can't be read would leave nothing behind once
+		// the environment is destroyed, so it's checked first.
+		if _, err := l.Load(); err != nil {
+			fmt.Printf("error reading config file: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		if env.Initialized() {
+			fmt.Println("destroying environment...")
+
+			if err := ctl.Remove(env.Container); err != nil {
+				fmt.Printf("error destroying environment: %v\n", err)
+				osExit(1)
+				return
+			}
+
+			if err := s.Delete(envName); err != nil {
+				fmt.Printf("error deleting data store: %v\n", err)
+				osExit(1)
+				return
+			}
+		}
+
+		createCmd.Run(createCmd, args)
+	}
+
+	return &cobra.Command{
+		Use:   "rebuild",
+		Short: rebuildDesc,
+		Long:  rebuildLongDesc,
+		Run:   runRebuild,
+	}
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestConfigChanged(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	attached := 0
+
+	ctl := newMockCtl(nil)
+	ctl.attachFn = func(m container.Metadata) error {
+		attached++
+		return nil
+	}
+
+	s := newMemStore(db.Environment{Status: db.StatusOff})
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+			Ports: config.Ports{"3000"},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	if s.env().ConfigHash == "" {
+		t.Fatal("config hash", "saved", s.env().ConfigHash)
+	}
+
+	out := runCmd(t, newStatusCmd(ctl, s, cfg))
+	if strings.Contains(out, "config file has changed") {
+		t.Fatal("status output", "no config change", out)
+	}
+
+	runCmd(t, newLoginCmd(ctl, s, cfg))
+	if 1 != attached || 0 != exitCode {
+		t.Fatal("logins", 1, attached)
+	}
+
+	// A teammate publishes another port.
+	cfg.opts.Ports = config.Ports{"3000", "8080"}
+
+	out = runCmd(t, newStatusCmd(ctl, s, cfg))
+	if !strings.Contains(out, "The config file has changed") {
+		t.Fatal("status output", "a config change", out)
+	}
+
+	cmd := newStatusCmd(ctl, s, cfg)
+	cmd.Flags().Set("output", "json")
+
+	var actual statusOutput
+	if err := json.Unmarshal([]byte(runCmd(t, cmd)), &actual); err != nil {
+		t.Fatal("decoding output", nil, err)
+	}
+
+	if !actual.ConfigChanged {
+		t.Fatal("config_changed", true, actual.ConfigChanged)
+	}
+
+	out = runCmd(t, newLoginCmd(ctl, s, cfg))
+	if 1 != attached || 1 != exitCode || !strings.Contains(out, "envctl rebuild") {
+		t.Fatal("login into a stale environment", "refused", out)
+	}
+	exitCode = 0
+
+	login := newLoginCmd(ctl, s, cfg)
+	login.Flags().Set("force", "true")
+
+	runCmd(t, login)
+	if 2 != attached || 0 != exitCode {
+		t.Fatal("logins with --force", 2, attached)
+	}
+}
+
+func TestRebuild(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:  "alpine",
+			Shell:  "/bin/sh",
+			Caches: []string{"/root/.npm"},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	before, _ := s.Read(envName)
+
+	cfg.opts.Variables = map[string]string{"NODE_ENV": "development"}
+
+	runCmd(t, newRebuildCmd(ctl, s, cfg))
+
+	after, _ := s.Read(envName)
+	if db.StatusReady != after.Status {
+		t.Fatal("status after rebuild", db.StatusReady, after.Status)
+	}
+
+	if before.Container.ID == after.Container.ID {
+		t.Fatal("container after rebuild", "a new one", after.Container.ID)
+	}
+
+	if len(after.Container.Envs) != 1 || "NODE_ENV=development" != after.Container.Envs[0] {
+		t.Fatal("envs after rebuild", "NODE_ENV=development", after.Container.Envs)
+	}
+
+	if state, _ := ctl.Inspect(before.Container); container.StatusMissing != state.Status {
+		t.Fatal("old container after rebuild", container.StatusMissing, state.Status)
+	}
+
+	vols, _ := ctl.Volumes(nil)
+	if 1 != len(vols) || after.Container.Mounts[0].Source != vols[0].Name {
+		t.Fatal("caches after rebuild", after.Container.Mounts, vols)
+	}
+
+	changed, err := configChanged(cfg, after)
+	if err != nil || changed {
+		t.Fatal("config changed after rebuild", false, changed)
+	}
+
+	// With nothing to destroy, it's the same as creating.
+	s.Delete(envName)
+
+	runCmd(t, newRebuildCmd(ctl, s, cfg))
+	if env, _ := s.Read(envName); db.StatusReady != env.Status || 0 != exitCode {
+		t.Fatal("status after rebuilding from nothing", db.StatusReady, env.Status)
+	}
+}
+
+func TestRebuildMissingContainer(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	before, _ := s.Read(envName)
+	ctl.Delete(before.Container.ID)
+
+	runCmd(t, newRebuildCmd(ctl, s, cfg))
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+
+	after, _ := s.Read(envName)
+	if db.StatusReady != after.Status || before.Container.ID == after.Container.ID {
+		t.Fatal("environment after rebuild", "a new, ready one", after)
+	}
+
+	if state, _ := ctl.Inspect(after.Container); container.StatusCreated != state.Status {
+		t.Fatal("container after rebuild", container.StatusCreated, state.Status)
+	}
+
+	if ctl.HasImage(before.Container.ImageID) {
+		t.Fatal("old image after rebuild", false, true)
+	}
+}
+
+func TestRebuildJSONStore(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	dir, err := ioutil.TempDir("", "envctl-rebuild")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The bootstrap script is written under the working directory, next to
+	// the store.
+	pwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal("getting working dir", nil, err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal("changing working dir", nil, err)
+	}
+	defer os.Chdir(pwd)
+
+	ctl := fake.NewController()
+	s, err := db.NewJSONStore(".envctl/")
+	if err != nil {
+		t.Fatal("creating store", nil, err)
+	}
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Image:     "alpine",
+			Shell:     "/bin/sh",
+			Bootstrap: []string{"echo hi"},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	before, _ := s.Read(envName)
+
+	// Deleting the only environment removes the store's directory, which
+	// the new environment's bootstrap script and record go in.
+	out := runCmd(t, newRebuildCmd(ctl, s, cfg))
+	if 0 != exitCode {
+		t.Fatal("rebuild exit code", 0, out)
+	}
+
+	after, err := s.Read(envName)
+	if err != nil || db.StatusReady != after.Status || before.Container.ID == after.Container.ID {
+		t.Fatal("environment after rebuild", "a new, ready one", after)
+	}
+
+	if 1 != ctl.Containers() {
+		t.Fatal("containers after rebuild", 1, ctl.Containers())
+	}
+}
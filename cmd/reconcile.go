@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+)
+
+// reconcile checks the stored state of an environment against what the
+// container engine says about its container, since either of them can be
+// changed behind envctl's back. It returns the environment with its status
+// repaired, along with a description of the drift that was found, if any.
+func reconcile(
+	ctl container.Controller,
+	env db.Environment,
+) (db.Environment, string, error) {
+	if !env.Initialized() {
+		return env, "", nil
+	}
+
+	state, err := ctl.Inspect(env.Container)
+	if err != nil {
+		return env, "", err
+	}
+
+	env, drift := reconcileState(env, state)
+	return env, drift, nil
+}
+
+// reconcileState is reconcile for when the state of the environment's
+// container has already been inspected.
+func reconcileState(
+	env db.Environment,
+	state container.State,
+) (db.Environment, string) {
+	if !env.Initialized() {
+		return env, ""
+	}
+
+	switch {
+	case state.Status == container.StatusMissing:
+		env.Status = db.StatusError
+		return env, "The environment's container has been removed."
+	case state.Status == container.StatusImageMissing:
+		env.Status = db.StatusError
+		return env, "The environment's image has been removed."
+	case state.Status == container.StatusRunning &&
+		env.Status == db.StatusStopped:
+
+		env.Status = db.StatusReady
+		return env, "The environment was started outside of envctl."
+	}
+
+	return env, ""
+}
+
+// configChanged reports whether the config file has changed since the
+// environment was created from it. Environments created before envctl kept
+// track of that are never reported as changed, since there's no telling.
+func configChanged(l config.Loader, env db.Environment) (bool, error) {
+	if !env.Initialized() || env.ConfigHash == "" {
+		return false, nil
+	}
+
+	cfg, err := l.Load()
+	if err != nil {
+		return false, err
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		return false, err
+	}
+
+	hash, err := config.Hash(cfg, pwd)
+	if err != nil {
+		return false, err
+	}
+
+	return hash != env.ConfigHash, nil
+}
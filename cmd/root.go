@@ -5,14 +5,25 @@ import (
 	"os"
 
 	"github.com/UltimateSoftware/envctl/internal/config"
-	"github.com/UltimateSoftware/envctl/internal/db"
 	"github.com/UltimateSoftware/envctl/pkg/container"
-	"github.com/UltimateSoftware/envctl/pkg/container/docker"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/spf13/cobra"
 )
 
 var cfgFile = "envctl.yaml"
 
+var envName = db.DefaultName
+
+// runtimeName overrides the runtime set in the config file, if it's set.
+var runtimeName string
+
+// dockerFlags override the Docker connection settings in the config file.
+var dockerFlags config.Docker
+
+// osExit is used instead of os.Exit by commands whose exit codes are tested.
+// Tests replace it so that they don't exit along with the command.
+var osExit = os.Exit
+
 var rootDesc = "Control your development environments"
 
 var rootLongDesc = `envctl - Control your development environments
@@ -48,15 +59,67 @@ func Execute() {
 }
 
 func init() {
-	ctl := initCtl()
+	rootCmd.PersistentFlags().StringVarP(
+		&envName,
+		"env",
+		"e",
+		db.DefaultName,
+		"name of the environment to use",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&runtimeName,
+		"runtime",
+		"",
+		"container runtime to use (default from config, or docker)",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&dockerFlags.Host,
+		"docker-host",
+		"",
+		"Docker daemon socket to connect to",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&dockerFlags.CertPath,
+		"docker-cert-path",
+		"",
+		"directory with the TLS certificates for the Docker daemon",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&dockerFlags.APIVersion,
+		"docker-api-version",
+		"",
+		"Docker Engine API version to use",
+	)
+
+	rootCmd.PersistentFlags().StringVar(
+		&dockerFlags.Context,
+		"docker-context",
+		"",
+		"Docker context to connect with",
+	)
+
 	s := initStore()
 	l := initConfig()
+	ctl := initCtl(l)
 
 	rootCmd.AddCommand(newCreateCmd(ctl, s, l))
 	rootCmd.AddCommand(newDestroyCmd(ctl, s))
-	rootCmd.AddCommand(newStatusCmd(s))
+	rootCmd.AddCommand(newRebuildCmd(ctl, s, l))
+	rootCmd.AddCommand(newStopCmd(ctl, s))
+	rootCmd.AddCommand(newStartCmd(ctl, s))
+	rootCmd.AddCommand(newStatusCmd(ctl, s, l))
 	rootCmd.AddCommand(newInitCmd())
-	rootCmd.AddCommand(newLoginCmd(ctl, s))
+	rootCmd.AddCommand(newValidateCmd(l))
+	rootCmd.AddCommand(newLoginCmd(ctl, s, l))
+	rootCmd.AddCommand(newExecCmd(ctl, s))
+	rootCmd.AddCommand(newPortCmd(ctl, s))
+	rootCmd.AddCommand(newListCmd(s))
+	rootCmd.AddCommand(newRuntimesCmd(l))
+	rootCmd.AddCommand(newCacheCmd(ctl))
 	rootCmd.AddCommand(newVersionCmd())
 }
 
@@ -76,13 +139,9 @@ func initStore() db.Store {
 	return jsonStore
 }
 
-func initCtl() container.Controller {
-	var err error
-	ctl, err := docker.NewController()
-	if err != nil {
-		fmt.Printf("error creating Docker controller: %v\n", err)
-		os.Exit(1)
-	}
-
-	return ctl
+// initCtl returns a controller for whichever container runtime is in use.
+// The runtime isn't known until the flags have been parsed, so the actual
+// controller is only created once it's needed.
+func initCtl(l config.Loader) container.Controller {
+	return newRuntimeCtl(l)
 }
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+
+	// The built-in runtimes register themselves with the container package.
+	// Other runtimes can be added by importing them the same way.
+	_ "github.com/UltimateSoftware/envctl/pkg/container/docker"
+	_ "github.com/UltimateSoftware/envctl/pkg/container/podman"
+)
+
+const defaultRuntime = "docker"
+
+// runtimeCtl is a container.Controller that hands everything off to the
+// controller for the right container runtime. Containers that already exist
+// stay with the runtime and options they were created with. New ones use the
+// runtime from the --runtime flag, or the config file, or Docker.
+type runtimeCtl struct {
+	l    config.Loader
+	ctls map[string]container.Controller
+}
+
+func newRuntimeCtl(l config.Loader) *runtimeCtl {
+	return &runtimeCtl{
+		l:    l,
+		ctls: map[string]container.Controller{},
+	}
+}
+
+// runtime returns the name of the runtime the container with the given
+// metadata belongs to.
+func (r *runtimeCtl) runtime(m container.Metadata) string {
+	if m.Runtime != "" {
+		return m.Runtime
+	}
+
+	// Containers with an ID were created before runtimes could be chosen, so
+	// they're Docker containers.
+	if m.ID != "" {
+		return defaultRuntime
+	}
+
+	if runtimeName != "" {
+		return runtimeName
+	}
+
+	cfg, err := r.l.Load()
+	if err == nil && cfg.Runtime != "" {
+		return cfg.Runtime
+	}
+
+	return defaultRuntime
+}
+
+// options returns the options for the runtime with the given name, from the
+// config file and the command line. A config file that can't be loaded
+// doesn't have any.
+func (r *runtimeCtl) options(name string) container.Options {
+	opts := container.Options{}
+
+	cfg, err := r.l.Load()
+	if err == nil {
+		for k, v := range cfg.RuntimeOptions {
+			opts[k] = v
+		}
+	}
+
+	if name == "docker" {
+		// A host or context on the command line replaces the one from the
+		// config file entirely, since only one of them can be used.
+		docker := cfg.Docker
+		if dockerFlags.Host != "" || dockerFlags.Context != "" {
+			docker.Host = dockerFlags.Host
+			docker.Context = dockerFlags.Context
+		}
+
+		if dockerFlags.CertPath != "" {
+			docker.CertPath = dockerFlags.CertPath
+		}
+
+		if dockerFlags.APIVersion != "" {
+			docker.APIVersion = dockerFlags.APIVersion
+		}
+
+		setOpt(opts, "host", docker.Host)
+		setOpt(opts, "cert_path", docker.CertPath)
+		setOpt(opts, "api_version", docker.APIVersion)
+		setOpt(opts, "context", docker.Context)
+	}
+
+	return opts
+}
+
+func setOpt(opts container.Options, k, v string) {
+	if v != "" {
+		opts[k] = v
+	}
+}
+
+// containerOptions returns the options for the controller of the container
+// with the given metadata. Existing containers are only reachable with the
+// options they were created with, so a Docker host or context on the command
+// line that points somewhere else is an error rather than being ignored.
+func (r *runtimeCtl) containerOptions(m container.Metadata) (container.Options, error) {
+	name := r.runtime(m)
+
+	if m.ID == "" || m.RuntimeOptions == nil {
+		return r.options(name), nil
+	}
+
+	if name == "docker" && (dockerFlags.Host != "" || dockerFlags.Context != "") &&
+		(dockerFlags.Host != m.RuntimeOptions["host"] ||
+			dockerFlags.Context != m.RuntimeOptions["context"]) {
+
+		return nil, errors.New("the environment was created with another " +
+			"Docker host or context, leave out --docker-host and " +
+			"--docker-context to use the one it was created with")
+	}
+
+	return m.RuntimeOptions, nil
+}
+
+func (r *runtimeCtl) controller(m container.Metadata) (container.Controller, error) {
+	name := r.runtime(m)
+
+	opts, err := r.containerOptions(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Controllers are kept per runtime and options, since environments created
+	// with different options can be managed by the same command.
+	buf, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	key := name + string(buf)
+	if ctl, ok := r.ctls[key]; ok {
+		return ctl, nil
+	}
+
+	ctl, err := container.New(name, opts)
+	if err != nil {
+		return nil, fmt.Errorf("creating %v controller: %v", name, err)
+	}
+
+	r.ctls[key] = ctl
+	return ctl, nil
+}
+
+func (r *runtimeCtl) Create(m container.Metadata) (container.Metadata, error) {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return container.Metadata{}, err
+	}
+
+	m.Runtime = r.runtime(m)
+	if m.RuntimeOptions, err = r.containerOptions(m); err != nil {
+		return container.Metadata{}, err
+	}
+
+	return ctl.Create(m)
+}
+
+func (r *runtimeCtl) Remove(m container.Metadata) error {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return err
+	}
+
+	return ctl.Remove(m)
+}
+
+func (r *runtimeCtl) Start(m container.Metadata) error {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return err
+	}
+
+	return ctl.Start(m)
+}
+
+func (r *runtimeCtl) Stop(m container.Metadata) error {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return err
+	}
+
+	return ctl.Stop(m)
+}
+
+func (r *runtimeCtl) Attach(m container.Metadata) error {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return err
+	}
+
+	return ctl.Attach(m)
+}
+
+func (r *runtimeCtl) Run(
+	m container.Metadata,
+	cmd []string,
+	opts container.RunOpts,
+) error {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return err
+	}
+
+	return ctl.Run(m, cmd, opts)
+}
+
+func (r *runtimeCtl) Inspect(m container.Metadata) (container.State, error) {
+	ctl, err := r.controller(m)
+	if err != nil {
+		return container.State{}, err
+	}
+
+	return ctl.Inspect(m)
+}
+
+// volumeManager returns the controller new environments get created with, as
+// long as its runtime can manage volumes.
+func (r *runtimeCtl) volumeManager() (container.VolumeManager, error) {
+	m := container.Metadata{}
+
+	ctl, err := r.controller(m)
+	if err != nil {
+		return nil, err
+	}
+
+	vm, ok := ctl.(container.VolumeManager)
+	if !ok {
+		return nil, fmt.Errorf("the %v runtime can't manage volumes", r.runtime(m))
+	}
+
+	return vm, nil
+}
+
+func (r *runtimeCtl) CreateVolume(v container.Volume) error {
+	vm, err := r.volumeManager()
+	if err != nil {
+		return err
+	}
+
+	return vm.CreateVolume(v)
+}
+
+func (r *runtimeCtl) Volumes(labels map[string]string) ([]container.Volume, error) {
+	vm, err := r.volumeManager()
+	if err != nil {
+		return nil, err
+	}
+
+	return vm.Volumes(labels)
+}
+
+func (r *runtimeCtl) RemoveVolume(name string) error {
+	vm, err := r.volumeManager()
+	if err != nil {
+		return err
+	}
+
+	return vm.RemoveVolume(name)
+}
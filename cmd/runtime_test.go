@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+// optsCtl is a controller that remembers the options it was created with.
+type optsCtl struct {
+	*mockCtl
+	opts container.Options
+}
+
+func init() {
+	container.Register("test-options", func(opts container.Options) (container.Controller, error) {
+		return optsCtl{mockCtl: newMockCtl(nil), opts: opts}, nil
+	})
+}
+
+func TestRuntimeSelection(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	defer func() { runtimeName = "" }()
+
+	cases := []struct {
+		name     string
+		flag     string
+		config   string
+		meta     container.Metadata
+		expected string
+	}{
+		{name: "default", expected: "docker"},
+		{name: "config", config: "podman", expected: "podman"},
+		{name: "flag", flag: "docker", config: "podman", expected: "docker"},
+		{
+			name:     "existing container",
+			flag:     "docker",
+			meta:     container.Metadata{ID: "foocnt", Runtime: "podman"},
+			expected: "podman",
+		},
+		{
+			name:     "container from before runtimes",
+			config:   "podman",
+			meta:     container.Metadata{ID: "foocnt"},
+			expected: "docker",
+		},
+	}
+
+	for _, c := range cases {
+		runtimeName = c.flag
+		r := newRuntimeCtl(memConfig{opts: config.Opts{Runtime: c.config}})
+
+		if actual := r.runtime(c.meta); c.expected != actual {
+			t.Fatal(c.name, c.expected, actual)
+		}
+	}
+}
+
+func TestUnknownRuntime(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	r := newRuntimeCtl(memConfig{})
+
+	_, err := r.Inspect(container.Metadata{ID: "foocnt", Runtime: "lxc"})
+	if err == nil {
+		t.Fatal("Inspect() error", "unknown runtime", err)
+	}
+}
+
+func TestDockerOptions(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	defer func() { dockerFlags = config.Docker{} }()
+
+	r := newRuntimeCtl(memConfig{
+		opts: config.Opts{
+			RuntimeOptions: map[string]string{"socket": "/run/podman.sock"},
+			Docker: config.Docker{
+				Host:     "tcp://build-host:2376",
+				CertPath: "certs",
+			},
+		},
+	})
+
+	dockerFlags = config.Docker{APIVersion: "1.25"}
+	expected := container.Options{
+		"socket":      "/run/podman.sock",
+		"host":        "tcp://build-host:2376",
+		"cert_path":   "certs",
+		"api_version": "1.25",
+	}
+
+	if actual := r.options("docker"); !equalOptions(expected, actual) {
+		t.Fatal("docker options", expected, actual)
+	}
+
+	// A context on the command line wins over the host in the config file.
+	dockerFlags = config.Docker{Context: "build"}
+	expected = container.Options{
+		"socket":    "/run/podman.sock",
+		"cert_path": "certs",
+		"context":   "build",
+	}
+
+	if actual := r.options("docker"); !equalOptions(expected, actual) {
+		t.Fatal("docker options with context", expected, actual)
+	}
+
+	expected = container.Options{"socket": "/run/podman.sock"}
+	if actual := r.options("podman"); !equalOptions(expected, actual) {
+		t.Fatal("podman options", expected, actual)
+	}
+}
+
+func equalOptions(a, b container.Options) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestRuntimeOptionsKept(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cfg := memConfig{
+		opts: config.Opts{
+			Runtime:        "test-options",
+			RuntimeOptions: map[string]string{"socket": "/run/a.sock"},
+		},
+	}
+
+	m, err := newRuntimeCtl(cfg).Create(container.Metadata{})
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	expected := container.Options{"socket": "/run/a.sock"}
+	if "test-options" != m.Runtime || !equalOptions(expected, m.RuntimeOptions) {
+		t.Fatal("saved runtime options", expected, m.RuntimeOptions)
+	}
+
+	// The container stays where it was created, whatever the config says now.
+	cfg.opts.RuntimeOptions = map[string]string{"socket": "/run/b.sock"}
+	r := newRuntimeCtl(cfg)
+
+	ctl, err := r.controller(m)
+	if err != nil {
+		t.Fatal("controller()", nil, err)
+	}
+
+	if actual := ctl.(optsCtl).opts; !equalOptions(expected, actual) {
+		t.Fatal("options of an existing container", expected, actual)
+	}
+
+	// Containers from before the options were saved use the current ones.
+	m.RuntimeOptions = nil
+
+	ctl, err = r.controller(m)
+	if err != nil {
+		t.Fatal("controller()", nil, err)
+	}
+
+	expected = container.Options{"socket": "/run/b.sock"}
+	if actual := ctl.(optsCtl).opts; !equalOptions(expected, actual) {
+		t.Fatal("options of a container without saved ones", expected, actual)
+	}
+}
+
+func TestDockerHostMismatch(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	defer func() { dockerFlags = config.Docker{} }()
+
+	m := container.Metadata{
+		ID:             "foocnt",
+		Runtime:        "docker",
+		RuntimeOptions: container.Options{"host": "tcp://build-host:2376"},
+	}
+
+	dockerFlags = config.Docker{Host: "tcp://other-host:2376"}
+
+	_, err := newRuntimeCtl(memConfig{}).Inspect(m)
+	if err == nil || !strings.Contains(err.Error(), "another Docker host or context") {
+		t.Fatal("Inspect() error", "another Docker host or context", err)
+	}
+
+	dockerFlags = config.Docker{Host: "tcp://build-host:2376"}
+
+	opts, err := newRuntimeCtl(memConfig{}).containerOptions(m)
+	if err != nil || !equalOptions(m.RuntimeOptions, opts) {
+		t.Fatal("options with the same host", m.RuntimeOptions, opts)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/spf13/cobra"
+)
+
+func newRuntimesCmd(l config.Loader) *cobra.Command {
+	runtimesDesc := "list the available container runtimes"
+
+	runtimesLongDesc := `runtimes - List the available container runtimes
+
+"runtimes" shows every container runtime envctl knows about, and whether it
+can be reached. The runtime new environments get created with is marked with a
+"*".`
+
+	runRuntimes := func(cmd *cobra.Command, args []string) {
+		r := newRuntimeCtl(l)
+		current := r.runtime(container.Metadata{})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tSTATUS")
+		for _, name := range container.Runtimes() {
+			mark := ""
+			if name == current {
+				mark = "*"
+			}
+
+			fmt.Fprintf(w, "%v\t%v\t%v\n", mark, name, runtimeStatus(name, r.options(name)))
+		}
+		w.Flush()
+	}
+
+	return &cobra.Command{
+		Use:   "runtimes",
+		Short: runtimesDesc,
+		Long:  runtimesLongDesc,
+		Run:   runRuntimes,
+	}
+}
+
+// runtimeStatus describes whether the runtime with the given name can be
+// reached. Runtimes that can't be asked are reported as unknown.
+func runtimeStatus(name string, opts container.Options) string {
+	ctl, err := container.New(name, opts)
+	if err != nil {
+		return fmt.Sprintf("unavailable (%v)", err)
+	}
+
+	p, ok := ctl.(container.Pinger)
+	if !ok {
+		return "unknown"
+	}
+
+	if err := p.Ping(); err != nil {
+		return fmt.Sprintf("unreachable (%v)", err)
+	}
+
+	return "reachable"
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+type pingCtl struct {
+	container.Controller
+	err error
+}
+
+func (c pingCtl) Ping() error {
+	return c.err
+}
+
+func init() {
+	container.Register("test-up", func(container.Options) (container.Controller, error) {
+		return pingCtl{}, nil
+	})
+
+	container.Register("test-down", func(container.Options) (container.Controller, error) {
+		return pingCtl{err: errors.New("connection refused")}, nil
+	})
+
+	container.Register("test-noping", func(container.Options) (container.Controller, error) {
+		return newMockCtl(nil), nil
+	})
+
+	container.Register("test-broken", func(container.Options) (container.Controller, error) {
+		return nil, errors.New("missing socket")
+	})
+}
+
+func TestRuntimes(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	runtimeName = "test-up"
+	defer func() { runtimeName = "" }()
+
+	cmd := newRuntimesCmd(memConfig{})
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	var actual string
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case out := <-outch:
+		actual = string(out)
+	}
+
+	expected := map[string]string{
+		"test-up":     "*  test-up",
+		"test-down":   "unreachable (connection refused)",
+		"test-noping": "unknown",
+		"test-broken": "unavailable (missing socket)",
+	}
+
+	for name, status := range expected {
+		found := false
+		for _, line := range strings.Split(actual, "\n") {
+			if strings.Contains(line, name+" ") && strings.Contains(line, status) {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatal("output for "+name, status, actual)
+		}
+	}
+}
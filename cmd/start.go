@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/spf13/cobra"
+)
+
+func newStartCmd(ctl container.Controller, s db.Store) *cobra.Command {
+	startDesc := "start a stopped environment"
+
+	startLongDesc := `start - Start a stopped environment
+
+"start" will bring back an environment that was stopped with "envctl stop",
+exactly as it was left.`
+
+	msgEnvOff := `The environment is off!
+
+To create it, run "envctl create".`
+
+	msgEnvNotStopped := `The environment isn't stopped.
+
+Run "envctl status" to see what state it's in.`
+
+	runStart := func(cmd *cobra.Command, args []string) {
+		env, err := s.Read(envName)
+		if err != nil {
+			fmt.Printf("error reading data store: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !env.Initialized() {
+			fmt.Println(msgEnvOff)
+			os.Exit(1)
+		}
+
+		if env.Status != db.StatusStopped {
+			fmt.Println(msgEnvNotStopped)
+			return
+		}
+
+		fmt.Println("starting environment...")
+
+		if err := ctl.Start(env.Container); err != nil {
+			fmt.Printf("error starting environment: %v\n", err)
+			os.Exit(1)
+		}
+
+		env.Status = db.StatusReady
+		if err := s.Create(envName, env); err != nil {
+			fmt.Printf("error saving environment: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	return &cobra.Command{
+		Use:   "start",
+		Short: startDesc,
+		Long:  startLongDesc,
+		Run:   runStart,
+	}
+}
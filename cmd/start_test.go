@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestStart(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cnt := container.Metadata{
+		ID:        "foocnt",
+		ImageID:   "fooimg",
+		BaseName:  "fooenv",
+		BaseImage: "scratch",
+		Shell:     "/foo/sh",
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusStopped,
+		Container: cnt,
+	})
+
+	ctl := newMockCtl(&cnt)
+
+	var started *container.Metadata
+	ctl.startFn = func(m container.Metadata) error {
+		started = &m
+		return nil
+	}
+
+	cmd := newStartCmd(ctl, s)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if started == nil || started.ID != cnt.ID {
+		t.Fatal("started container", cnt, started)
+	}
+
+	if db.StatusReady != s.env().Status {
+		t.Fatal("status", db.StatusReady, s.env().Status)
+	}
+}
+
+func TestStartNotStopped(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cnt := container.Metadata{
+		ID: "foocnt",
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	ctl := newMockCtl(&cnt)
+
+	started := false
+	ctl.startFn = func(m container.Metadata) error {
+		started = true
+		return nil
+	}
+
+	cmd := newStartCmd(ctl, s)
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if started {
+		t.Fatal("starting a ready environment", false, started)
+	}
+}
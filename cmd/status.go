@@ -1,14 +1,35 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 
-	"github.com/UltimateSoftware/envctl/internal/db"
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	units "github.com/docker/go-units"
 	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
 )
 
-func newStatusCmd(s db.Store) *cobra.Command {
+// statusExitCodes are the exit codes of "envctl status" for every state that
+// isn't "ready", so that scripts can branch on the state without having to
+// parse the output. 1 is left for errors that keep envctl from checking the
+// status at all.
+var statusExitCodes = map[int]int{
+	db.StatusOff:     3,
+	db.StatusError:   4,
+	db.StatusStopped: 5,
+}
+
+func newStatusCmd(
+	ctl container.Controller,
+	s db.Store,
+	l config.Loader,
+) *cobra.Command {
+	var output string
+
 	statusDesc := "get current environment's status"
 
 	statusLongDesc := `status - Get the current environment's status
@@ -16,12 +37,34 @@ func newStatusCmd(s db.Store) *cobra.Command {
 Environments can be in different states:
 - "ready": the environment is ready for use
 - "error": the environment is in a bad state
+- "stopped": the environment has been stopped, but not destroyed
 - "off": the environment hasn't been created yet
 
 To move from "off" to "ready" state, run "envctl create".
 
+To move from "stopped" to "ready" state, run "envctl start".
+
 To fix "error" state, you can try recreating the environment with
-"envctl destroy" followed by "envctl create".`
+"envctl destroy" followed by "envctl create".
+
+The status is checked against the container engine, so if the environment's
+container or image was removed outside of envctl, it's reported and the
+environment is put into "error" state. One that was started outside of envctl
+is put back into "ready" state. A container that exited on its own, like when
+the shell of "envctl login" exits, is still "ready", since logging in starts
+it again. If the environment has a "ready" probe, it's run once, and whether
+it passes is reported along with the status. If the config file has changed
+since the environment was created, that's reported as well, and "envctl
+rebuild" recreates the environment from it.
+
+Its exit code also depends on the state, so scripts can branch on it:
+- 0: "ready"
+- 3: "off"
+- 4: "error"
+- 5: "stopped"
+
+With "--output json" or "--output yaml", the whole environment is printed in
+that format instead.`
 
 	statusReady := `The environment is ready!
 
@@ -31,31 +74,368 @@ Run "envctl login" to enter it.`
 
 Try recreating it by running "envctl destroy", followed by "envctl create".`
 
+	statusStopped := `The environment is stopped.
+
+Run "envctl start" to bring it back.`
+
+	statusConfigChanged := `The config file has changed since the environment was created.
+
+Run "envctl rebuild" to recreate the environment from it.`
+
 	statusOff := `The environment is off.
 
 Run "envctl create" to spin it up!`
 
 	runStatus := func(cmd *cobra.Command, args []string) {
-		env, err := s.Read()
+		if output != "" && output != "json" && output != "yaml" {
+			fmt.Printf("unknown output format %q\n", output)
+			os.Exit(1)
+		}
+
+		env, err := s.Read(envName)
 		if err != nil {
 			fmt.Printf("error reading data store: %v\n", err)
 			os.Exit(1)
 		}
 
-		switch env.Status {
-		case db.StatusReady:
-			fmt.Println(statusReady)
-		case db.StatusError:
-			fmt.Println(statusError)
-		case db.StatusOff:
-			fmt.Println(statusOff)
+		// The container is only inspected once, and everything reported
+		// about it comes from that.
+		var state container.State
+		if env.Initialized() {
+			state, err = ctl.Inspect(env.Container)
+			if err != nil {
+				fmt.Printf("error inspecting environment: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		env, drift := reconcileState(env, state)
+
+		if drift != "" {
+			if output == "" {
+				fmt.Printf("%v\n\n", drift)
+			}
+
+			if err := s.Create(envName, env); err != nil {
+				fmt.Printf("error saving environment: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// A config file that can't be read doesn't keep the status from being
+		// reported, since the status doesn't depend on it.
+		changed, err := configChanged(l, env)
+		if err != nil && output == "" {
+			fmt.Printf("error checking config file for changes: %v\n\n", err)
+		}
+
+		if changed && output == "" {
+			fmt.Printf("%v\n\n", statusConfigChanged)
+		}
+
+		ports := publishedPorts(env, state)
+		ready := checkReady(ctl, env, state)
+
+		switch output {
+		case "json":
+			buf, err := json.MarshalIndent(newStatusOutput(env, drift, changed, ports, ready), "", "  ")
+			if err != nil {
+				fmt.Printf("error encoding status: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Println(string(buf))
+		case "yaml":
+			buf, err := yaml.Marshal(newStatusOutput(env, drift, changed, ports, ready))
+			if err != nil {
+				fmt.Printf("error encoding status: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Print(string(buf))
+		default:
+			switch env.Status {
+			case db.StatusReady:
+				fmt.Println(statusReady)
+
+				if len(ports) > 0 {
+					fmt.Println("\nPorts:")
+					for _, p := range ports {
+						fmt.Printf("  %v\n", p)
+					}
+				}
+
+				printResources(env.Container.Resources)
+
+				if len(env.Container.Services) > 0 {
+					fmt.Println("\nServices:")
+					for _, svc := range env.Container.Services {
+						fmt.Printf("  %v (%v)\n", svc.Name, svc.Image)
+					}
+				}
+
+				if ready != nil {
+					fmt.Println("\nReady probe:")
+					if ready.Passing {
+						fmt.Printf("  %v: passing\n", ready.Probe)
+					} else {
+						fmt.Printf("  %v: failing, %v\n", ready.Probe, ready.Error)
+					}
+				}
+			case db.StatusError:
+				fmt.Println(statusError)
+			case db.StatusStopped:
+				fmt.Println(statusStopped)
+			case db.StatusOff:
+				fmt.Println(statusOff)
+			}
+		}
+
+		if code := statusExitCodes[env.Status]; code != 0 {
+			osExit(code)
 		}
 	}
 
-	return &cobra.Command{
+	statusCmd := &cobra.Command{
 		Use:   "status",
 		Short: statusDesc,
 		Long:  statusLongDesc,
 		Run:   runStatus,
 	}
+
+	statusCmd.Flags().StringVarP(
+		&output,
+		"output",
+		"o",
+		"",
+		"output format, either \"json\" or \"yaml\"",
+	)
+
+	return statusCmd
+}
+
+// statusOutput is what "envctl status" prints when asked for machine-readable
+// output.
+type statusOutput struct {
+	Name          string           `json:"name" yaml:"name"`
+	Status        string           `json:"status" yaml:"status"`
+	Drift         string           `json:"drift,omitempty" yaml:"drift,omitempty"`
+	ConfigChanged bool             `json:"config_changed,omitempty" yaml:"config_changed,omitempty"`
+	ContainerID   string           `json:"container_id" yaml:"container_id"`
+	ImageID       string           `json:"image_id" yaml:"image_id"`
+	BaseImage     string           `json:"base_image" yaml:"base_image"`
+	Mount         statusMount      `json:"mount" yaml:"mount"`
+	Mounts        []statusMount    `json:"mounts,omitempty" yaml:"mounts,omitempty"`
+	Ports         []statusPort     `json:"ports" yaml:"ports"`
+	Resources     *statusResources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Services      []statusService  `json:"services,omitempty" yaml:"services,omitempty"`
+	Network       *statusNetwork   `json:"network,omitempty" yaml:"network,omitempty"`
+	Ready         *statusReady     `json:"ready,omitempty" yaml:"ready,omitempty"`
+	User          string           `json:"user" yaml:"user"`
+	Shell         string           `json:"shell" yaml:"shell"`
+}
+
+// statusNetwork is the environment's own network, along with the names it can
+// be reached at and the external networks it joined.
+type statusNetwork struct {
+	Name     string   `json:"name" yaml:"name"`
+	Hostname string   `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Aliases  []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	External []string `json:"external,omitempty" yaml:"external,omitempty"`
+}
+
+// statusReady is the result of running the environment's ready probe. Probe
+// describes what the probe checks.
+type statusReady struct {
+	Probe   string `json:"probe" yaml:"probe"`
+	Passing bool   `json:"passing" yaml:"passing"`
+	Error   string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// statusService is a service running alongside the environment. Name is also
+// the service's hostname on the environment's network.
+type statusService struct {
+	Name        string `json:"name" yaml:"name"`
+	Image       string `json:"image" yaml:"image"`
+	ContainerID string `json:"container_id" yaml:"container_id"`
+}
+
+type statusMount struct {
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
+	Source      string `json:"source" yaml:"source"`
+	Destination string `json:"destination" yaml:"destination"`
+	ReadOnly    bool   `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+// statusPort is a published port. A HostPort of 0 means the container engine
+// picks one once the environment is started.
+type statusPort struct {
+	HostIP        string `json:"host_ip,omitempty" yaml:"host_ip,omitempty"`
+	HostPort      int    `json:"host_port" yaml:"host_port"`
+	ContainerPort int    `json:"container_port" yaml:"container_port"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+}
+
+// statusResources are the environment's resource limits. Memory and ShmSize
+// are in bytes.
+type statusResources struct {
+	CPUs      float64 `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	Memory    int64   `json:"memory,omitempty" yaml:"memory,omitempty"`
+	PidsLimit int64   `json:"pids_limit,omitempty" yaml:"pids_limit,omitempty"`
+	ShmSize   int64   `json:"shm_size,omitempty" yaml:"shm_size,omitempty"`
+}
+
+func newStatusOutput(
+	env db.Environment,
+	drift string,
+	changed bool,
+	published []container.PortBinding,
+	ready *statusReady,
+) statusOutput {
+	var mounts []statusMount
+	for _, m := range env.Container.Mounts {
+		mounts = append(mounts, statusMount{
+			Type:        string(m.Type),
+			Source:      m.Source,
+			Destination: m.Destination,
+			ReadOnly:    m.ReadOnly,
+		})
+	}
+
+	ports := []statusPort{}
+	for _, p := range published {
+		ports = append(ports, statusPort{
+			HostIP:        p.HostIP,
+			HostPort:      p.HostPort,
+			ContainerPort: p.ContainerPort,
+			Protocol:      p.Protocol,
+		})
+	}
+
+	var resources *statusResources
+	if res := env.Container.Resources; res != (container.Resources{}) {
+		resources = &statusResources{
+			CPUs:      float64(res.NanoCPUs) / 1e9,
+			Memory:    res.Memory,
+			PidsLimit: res.PidsLimit,
+			ShmSize:   res.ShmSize,
+		}
+	}
+
+	var services []statusService
+	for _, svc := range env.Container.Services {
+		services = append(services, statusService{
+			Name:        svc.Name,
+			Image:       svc.Image,
+			ContainerID: svc.ID,
+		})
+	}
+
+	// Environments created before they had networks of their own are on the
+	// default one.
+	var network *statusNetwork
+	if env.Container.Network != "" {
+		network = &statusNetwork{
+			Name:     env.Container.Network,
+			Hostname: env.Container.Hostname,
+			Aliases:  env.Container.Aliases,
+			External: env.Container.ExternalNetworks,
+		}
+	}
+
+	return statusOutput{
+		Name:          env.Name,
+		Status:        db.StatusName(env.Status),
+		Drift:         drift,
+		ConfigChanged: changed,
+		ContainerID:   env.Container.ID,
+		ImageID:       env.Container.ImageID,
+		BaseImage:     env.Container.BaseImage,
+		Mount: statusMount{
+			Source:      env.Container.Mount.Source,
+			Destination: env.Container.Mount.Destination,
+		},
+		Mounts:    mounts,
+		Ports:     ports,
+		Resources: resources,
+		Services:  services,
+		Network:   network,
+		Ready:     ready,
+		User:      env.Container.User,
+		Shell:     env.Container.Shell,
+	}
+}
+
+// publishedPorts returns the ports the environment's container has published,
+// with the host ports the container engine picked filled in. If they can't be
+// found out, like when the environment isn't running, it falls back to the
+// ports the environment was created with.
+func publishedPorts(
+	env db.Environment,
+	state container.State,
+) []container.PortBinding {
+	if env.Status == db.StatusReady && len(state.Ports) > 0 {
+		return state.Ports
+	}
+
+	return env.Container.Ports
+}
+
+// checkReady runs the environment's ready probe once, if it has one. It's only
+// run while the environment's container is running, since running a command
+// probe would start it otherwise.
+func checkReady(
+	ctl container.Controller,
+	env db.Environment,
+	state container.State,
+) *statusReady {
+	p := env.Container.Ready
+	if !env.Initialized() || p == nil {
+		return nil
+	}
+
+	ready := &statusReady{Passing: true}
+	switch {
+	case p.Command != "":
+		ready.Probe = fmt.Sprintf("command %q", p.Command)
+	case p.Port != 0:
+		ready.Probe = fmt.Sprintf("port %v", p.Port)
+	default:
+		ready.Probe = p.URL
+	}
+
+	if state.Status != container.StatusRunning {
+		ready.Passing = false
+		ready.Error = "not running"
+	} else if err := probeReady(ctl, env.Container, state); err != nil {
+		ready.Passing = false
+		ready.Error = err.Error()
+	}
+
+	return ready
+}
+
+// printResources prints the resource limits that are set, if there are any.
+func printResources(res container.Resources) {
+	if res == (container.Resources{}) {
+		return
+	}
+
+	fmt.Println("\nResource limits:")
+
+	if res.NanoCPUs != 0 {
+		fmt.Printf("  cpus: %v\n", float64(res.NanoCPUs)/1e9)
+	}
+
+	if res.Memory != 0 {
+		fmt.Printf("  memory: %v\n", units.BytesSize(float64(res.Memory)))
+	}
+
+	if res.PidsLimit != 0 {
+		fmt.Printf("  pids: %v\n", res.PidsLimit)
+	}
+
+	if res.ShmSize != 0 {
+		fmt.Printf("  shm size: %v\n", units.BytesSize(float64(res.ShmSize)))
+	}
 }
@@ -1,22 +1,30 @@
 package cmd
 
 import (
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 
-	"github.com/UltimateSoftware/envctl/internal/db"
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/fake"
+	"github.com/UltimateSoftware/envctl/pkg/db"
 	"github.com/UltimateSoftware/envctl/test_pkg"
 )
 
 func TestOffStatus(got *testing.T) {
 	t := test_pkg.NewT(got)
 
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusOff,
-		},
-	}
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
 
-	cmd := newStatusCmd(s)
+	cnt := s.env().Container
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
 
 	outch, errch := test_pkg.HijackStdout(func() {
 		cmd.Run(cmd, []string{})
@@ -35,17 +43,23 @@ Run "envctl create" to spin it up!
 			t.Fatal("output", expected, string(actual))
 		}
 	}
+
+	if 3 != exitCode {
+		t.Fatal("exit code", 3, exitCode)
+	}
 }
 
 func TestReadyStatus(got *testing.T) {
 	t := test_pkg.NewT(got)
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusReady,
-		},
-	}
 
-	cmd := newStatusCmd(s)
+	exitCode := 0
+	defer stubExit(&exitCode)()
+	s := newMemStore(db.Environment{
+		Status: db.StatusReady,
+	})
+
+	cnt := s.env().Container
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
 
 	outch, errch := test_pkg.HijackStdout(func() {
 		cmd.Run(cmd, []string{})
@@ -64,17 +78,23 @@ Run "envctl login" to enter it.
 			t.Fatal("output", expected, string(actual))
 		}
 	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
 }
 
 func TestErrorStatus(got *testing.T) {
 	t := test_pkg.NewT(got)
-	s := &memStore{
-		env: db.Environment{
-			Status: db.StatusError,
-		},
-	}
 
-	cmd := newStatusCmd(s)
+	exitCode := 0
+	defer stubExit(&exitCode)()
+	s := newMemStore(db.Environment{
+		Status: db.StatusError,
+	})
+
+	cnt := s.env().Container
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
 
 	outch, errch := test_pkg.HijackStdout(func() {
 		cmd.Run(cmd, []string{})
@@ -93,4 +113,428 @@ Try recreating it by running "envctl destroy", followed by "envctl create".
 			t.Fatal("output", expected, string(actual))
 		}
 	}
+
+	if 4 != exitCode {
+		t.Fatal("exit code", 4, exitCode)
+	}
+}
+
+func TestStoppedStatus(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+	s := newMemStore(db.Environment{
+		Status: db.StatusStopped,
+	})
+
+	cnt := s.env().Container
+	ctl := newMockCtl(&cnt)
+	ctl.inspectFn = func(m container.Metadata) (container.State, error) {
+		return container.State{Status: container.StatusExited}, nil
+	}
+
+	cmd := newStatusCmd(ctl, s, memConfig{})
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := `The environment is stopped.
+
+Run "envctl start" to bring it back.
+`
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+
+	if 5 != exitCode {
+		t.Fatal("exit code", 5, exitCode)
+	}
+}
+
+func TestMissingContainerStatus(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+	s := newMemStore(db.Environment{
+		Status: db.StatusReady,
+		Container: container.Metadata{
+			ID: "foocnt",
+		},
+	})
+
+	// The controller doesn't know about any containers, as if it had been
+	// removed with "docker rm".
+	cmd := newStatusCmd(newMockCtl(nil), s, memConfig{})
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := `The environment's container has been removed.
+
+Something is wrong with the environment. :(
+
+Try recreating it by running "envctl destroy", followed by "envctl create".
+`
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+
+	if db.StatusError != s.env().Status {
+		t.Fatal("repaired status", db.StatusError, s.env().Status)
+	}
+
+	if 4 != exitCode {
+		t.Fatal("exit code", 4, exitCode)
+	}
+}
+
+func TestStartedOutsideStatus(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+	s := newMemStore(db.Environment{
+		Status: db.StatusStopped,
+		Container: container.Metadata{
+			ID: "foocnt",
+		},
+	})
+
+	cnt := s.env().Container
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if db.StatusReady != s.env().Status {
+		t.Fatal("repaired status", db.StatusReady, s.env().Status)
+	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+}
+
+func TestExitedStatus(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+	s := newMemStore(db.Environment{
+		Status: db.StatusReady,
+		Container: container.Metadata{
+			ID: "foocnt",
+		},
+	})
+
+	cnt := s.env().Container
+	ctl := newMockCtl(&cnt)
+	ctl.inspectFn = func(m container.Metadata) (container.State, error) {
+		return container.State{Status: container.StatusExited}, nil
+	}
+
+	out := runCmd(t, newStatusCmd(ctl, s, memConfig{}))
+
+	// The shell exiting at the end of a login exits the container too, which
+	// doesn't keep it from being logged in to again.
+	if db.StatusReady != s.env().Status || strings.Contains(out, "stopped") {
+		t.Fatal("status after the container exited", db.StatusReady, out)
+	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+}
+
+func TestJSONStatus(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cnt := container.Metadata{
+		ID:        "foocnt",
+		ImageID:   "fooimg",
+		BaseImage: "scratch",
+		Shell:     "/foo/sh",
+		User:      "foouser",
+		Mount: container.Mount{
+			Source:      "/foo/src",
+			Destination: "/foo/mnt",
+		},
+		Ports: []container.PortBinding{
+			{HostPort: 4567, ContainerPort: 4567, Protocol: "tcp"},
+		},
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
+	cmd.Flags().Set("output", "json")
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	var actual statusOutput
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case out := <-outch:
+		if err := json.Unmarshal(out, &actual); err != nil {
+			t.Fatal("decoding output", nil, err)
+		}
+	}
+
+	expected := statusOutput{
+		Name:        db.DefaultName,
+		Status:      "ready",
+		ContainerID: "foocnt",
+		ImageID:     "fooimg",
+		BaseImage:   "scratch",
+		Mount: statusMount{
+			Source:      "/foo/src",
+			Destination: "/foo/mnt",
+		},
+		Ports: []statusPort{
+			{HostPort: 4567, ContainerPort: 4567, Protocol: "tcp"},
+		},
+		User:  "foouser",
+		Shell: "/foo/sh",
+	}
+
+	if expected.Name != actual.Name ||
+		expected.Status != actual.Status ||
+		expected.ContainerID != actual.ContainerID ||
+		expected.ImageID != actual.ImageID ||
+		expected.BaseImage != actual.BaseImage ||
+		expected.Mount != actual.Mount ||
+		expected.User != actual.User ||
+		expected.Shell != actual.Shell ||
+		len(actual.Ports) != 1 ||
+		expected.Ports[0] != actual.Ports[0] {
+
+		t.Fatal("status output", expected, actual)
+	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+}
+
+func TestYAMLStatus(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	s := newMemStore(db.Environment{
+		Status: db.StatusOff,
+	})
+
+	cmd := newStatusCmd(newMockCtl(nil), s, memConfig{})
+	cmd.Flags().Set("output", "yaml")
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := `name: default
+status: "off"
+container_id: ""
+image_id: ""
+base_image: ""
+mount:
+  source: ""
+  destination: ""
+ports: []
+user: ""
+shell: ""
+`
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+
+	if 3 != exitCode {
+		t.Fatal("exit code", 3, exitCode)
+	}
+}
+
+// stubExit keeps commands from exiting the test binary, recording the exit
+// code they were called with in code instead. The returned function
+// puts things back the way they were.
+func stubExit(code *int) func() {
+	osExit = func(c int) {
+		*code = c
+	}
+
+	return func() {
+		osExit = os.Exit
+	}
+}
+
+func TestStatusPublishedPorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	ctl := fake.NewController()
+	s := db.NewMemStore()
+	cfg := memConfig{
+		opts: config.Opts{
+			Image: "alpine",
+			Shell: "/bin/sh",
+			Ports: config.Ports{"127.0.0.1:8080:80", "auto:3000"},
+		},
+	}
+
+	runCmd(t, newCreateCmd(ctl, s, cfg))
+
+	env, _ := s.Read(envName)
+	ctl.Start(env.Container)
+
+	out := runCmd(t, newStatusCmd(ctl, s, memConfig{}))
+	for _, expected := range []string{"127.0.0.1:8080->80/tcp", "32769->3000/tcp"} {
+		if !strings.Contains(out, expected) {
+			t.Fatal("status output", expected, out)
+		}
+	}
+
+	cmd := newStatusCmd(ctl, s, memConfig{})
+	cmd.Flags().Set("output", "json")
+
+	var actual statusOutput
+	if err := json.Unmarshal([]byte(runCmd(t, cmd)), &actual); err != nil {
+		t.Fatal("decoding output", nil, err)
+	}
+
+	expected := statusPort{HostPort: 32769, ContainerPort: 3000, Protocol: "tcp"}
+	if len(actual.Ports) != 2 || expected != actual.Ports[1] {
+		t.Fatal("published ports", expected, actual.Ports)
+	}
+}
+
+func TestStatusResources(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cnt := container.Metadata{
+		ID:      "foocnt",
+		ImageID: "fooimg",
+		Resources: container.Resources{
+			NanoCPUs: 1500000000,
+			Memory:   2 << 30,
+		},
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	out := runCmd(t, newStatusCmd(newMockCtl(&cnt), s, memConfig{}))
+	for _, expected := range []string{"cpus: 1.5", "memory: 2GiB"} {
+		if !strings.Contains(out, expected) {
+			t.Fatal("status output", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "pids") {
+		t.Fatal("status output", "no pids limit", out)
+	}
+
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
+	cmd.Flags().Set("output", "json")
+
+	var actual statusOutput
+	if err := json.Unmarshal([]byte(runCmd(t, cmd)), &actual); err != nil {
+		t.Fatal("decoding output", nil, err)
+	}
+
+	expected := statusResources{CPUs: 1.5, Memory: 2 << 30}
+	if actual.Resources == nil || expected != *actual.Resources {
+		t.Fatal("resources", expected, actual.Resources)
+	}
+}
+
+func TestStatusServices(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cnt := container.Metadata{
+		ID:      "foocnt",
+		ImageID: "fooimg",
+		Services: []container.Service{
+			{Name: "db", Image: "postgres:11", ID: "dbcnt"},
+		},
+		Network:  "foonet",
+		Hostname: "app",
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	out := runCmd(t, newStatusCmd(newMockCtl(&cnt), s, memConfig{}))
+	if !strings.Contains(out, "Services:\n  db (postgres:11)") {
+		t.Fatal("status output", "db (postgres:11)", out)
+	}
+
+	cmd := newStatusCmd(newMockCtl(&cnt), s, memConfig{})
+	cmd.Flags().Set("output", "json")
+
+	var actual statusOutput
+	if err := json.Unmarshal([]byte(runCmd(t, cmd)), &actual); err != nil {
+		t.Fatal("decoding output", nil, err)
+	}
+
+	expected := statusService{Name: "db", Image: "postgres:11", ContainerID: "dbcnt"}
+	if len(actual.Services) != 1 || expected != actual.Services[0] {
+		t.Fatal("services", expected, actual.Services)
+	}
+
+	if actual.Network == nil || "foonet" != actual.Network.Name ||
+		"app" != actual.Network.Hostname {
+
+		t.Fatal("network", "foonet with hostname app", actual.Network)
+	}
 }
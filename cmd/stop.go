@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/spf13/cobra"
+)
+
+func newStopCmd(ctl container.Controller, s db.Store) *cobra.Command {
+	stopDesc := "stop the current environment without destroying it"
+
+	stopLongDesc := `stop - Stop the current environment without destroying it
+
+"stop" will shut down the environment's container, but keep it and its image
+around. Anything changed inside of it is still there when it's brought back
+with "envctl start".`
+
+	msgEnvOff := `The environment is off!
+
+To create it, run "envctl create".`
+
+	msgEnvStopped := `The environment is already stopped.
+
+To start it again, run "envctl start".`
+
+	runStop := func(cmd *cobra.Command, args []string) {
+		env, err := s.Read(envName)
+		if err != nil {
+			fmt.Printf("error reading data store: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !env.Initialized() {
+			fmt.Println(msgEnvOff)
+			os.Exit(1)
+		}
+
+		if env.Status == db.StatusStopped {
+			fmt.Println(msgEnvStopped)
+			return
+		}
+
+		fmt.Println("stopping environment...")
+
+		if err := ctl.Stop(env.Container); err != nil {
+			fmt.Printf("error stopping environment: %v\n", err)
+			os.Exit(1)
+		}
+
+		env.Status = db.StatusStopped
+		if err := s.Create(envName, env); err != nil {
+			fmt.Printf("error saving environment: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	return &cobra.Command{
+		Use:   "stop",
+		Short: stopDesc,
+		Long:  stopLongDesc,
+		Run:   runStop,
+	}
+}
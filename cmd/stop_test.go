@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/db"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestStop(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	cnt := container.Metadata{
+		ID:        "foocnt",
+		ImageID:   "fooimg",
+		BaseName:  "fooenv",
+		BaseImage: "scratch",
+		Shell:     "/foo/sh",
+	}
+
+	s := newMemStore(db.Environment{
+		Status:    db.StatusReady,
+		Container: cnt,
+	})
+
+	ctl := newMockCtl(&cnt)
+
+	var stopped *container.Metadata
+	ctl.stopFn = func(m container.Metadata) error {
+		stopped = &m
+		return nil
+	}
+
+	cmd := newStopCmd(ctl, s)
+
+	// Hijacking here swallows the command output so that it doesn't clutter
+	// the output of `go test -v ./...`.
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case <-outch:
+	}
+
+	if stopped == nil || stopped.ID != cnt.ID {
+		t.Fatal("stopped container", cnt, stopped)
+	}
+
+	if db.StatusStopped != s.env().Status {
+		t.Fatal("status", db.StatusStopped, s.env().Status)
+	}
+
+	if cnt.ID != s.env().Container.ID {
+		t.Fatal("container id", cnt.ID, s.env().Container.ID)
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd(l config.Loader) *cobra.Command {
+	validateDesc := "check the config file for problems"
+
+	validateLongDesc := `validate - Check the config file for problems
+
+"validate" reads the config file and reports every problem it finds with it,
+along with the line it's on. It exits with a non-zero exit code if there are
+any, which makes it useful in pre-commit hooks.`
+
+	runValidate := func(cmd *cobra.Command, args []string) {
+		_, err := l.Load()
+		if err == nil {
+			fmt.Printf("%v is valid!\n", cfgFile)
+			return
+		}
+
+		ps, ok := err.(config.Problems)
+		if !ok {
+			fmt.Printf("error reading config file: %v\n", err)
+			osExit(1)
+			return
+		}
+
+		for _, p := range ps {
+			if p.Line == 0 {
+				fmt.Printf("%v: %v\n", cfgFile, p.Message)
+			} else {
+				fmt.Printf("%v:%v: %v\n", cfgFile, p.Line, p.Message)
+			}
+		}
+
+		osExit(1)
+	}
+
+	return &cobra.Command{
+		Use:   "validate",
+		Short: validateDesc,
+		Long:  validateLongDesc,
+		Run:   runValidate,
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/internal/config"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestValidate(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cfgFile = "envctl.yaml.test"
+	defer func() {
+		cfgFile = "envctl.yaml"
+		os.Remove("envctl.yaml.test")
+	}()
+
+	raw := `---
+image: ubuntu:latest
+
+ports:
+  tcp:
+  - 0
+`
+
+	if err := ioutil.WriteFile(cfgFile, []byte(raw), 0644); err != nil {
+		t.Fatal("writing test config", nil, err)
+	}
+
+	cmd := newValidateCmd(config.YAML{Path: cfgFile})
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := `envctl.yaml.test: missing shell
+envctl.yaml.test:6: port 0 is out of range, must be between 1 and 65535
+`
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+
+	if 1 != exitCode {
+		t.Fatal("exit code", 1, exitCode)
+	}
+}
+
+func TestValidateValid(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	exitCode := 0
+	defer stubExit(&exitCode)()
+
+	cfgFile = "envctl.yaml.test"
+	defer func() {
+		cfgFile = "envctl.yaml"
+		os.Remove("envctl.yaml.test")
+	}()
+
+	raw := `---
+image: ubuntu:latest
+shell: /bin/bash
+`
+
+	if err := ioutil.WriteFile(cfgFile, []byte(raw), 0644); err != nil {
+		t.Fatal("writing test config", nil, err)
+	}
+
+	cmd := newValidateCmd(config.YAML{Path: cfgFile})
+
+	outch, errch := test_pkg.HijackStdout(func() {
+		cmd.Run(cmd, []string{})
+	})
+
+	expected := "envctl.yaml.test is valid!\n"
+
+	select {
+	case err := <-errch:
+		t.Fatal("hijacking output", nil, err)
+	case actual := <-outch:
+		if expected != string(actual) {
+			t.Fatal("output", expected, string(actual))
+		}
+	}
+
+	if 0 != exitCode {
+		t.Fatal("exit code", 0, exitCode)
+	}
+}
@@ -8,7 +8,7 @@ require (
 	github.com/docker/distribution v2.6.2+incompatible // indirect
 	github.com/docker/docker v1.13.1
 	github.com/docker/go-connections v0.3.0
-	github.com/docker/go-units v0.3.2 // indirect
+	github.com/docker/go-units v0.3.2
 	github.com/google/uuid v0.0.0-20161128191214-064e2069ce9c
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/onsi/ginkgo v1.7.0 // indirect
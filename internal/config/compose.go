@@ -0,0 +1,461 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// composeFile is the part of a docker-compose file that envctl understands.
+// Anything else in it is ignored, since compose files are full of settings
+// that only make sense to docker-compose.
+type composeFile struct {
+	// Name is the project's name. It defaults to the name of the compose
+	// file's directory, like with docker-compose.
+	Name     string                    `yaml:"name"`
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]*composeVolume `yaml:"volumes"`
+}
+
+type composeService struct {
+	Image       string              `yaml:"image"`
+	Build       interface{}         `yaml:"build"`
+	Environment composeEnvironment  `yaml:"environment"`
+	Ports       Ports               `yaml:"ports"`
+	Volumes     []string            `yaml:"volumes"`
+	DependsOn   composeDependencies `yaml:"depends_on"`
+	Healthcheck *composeHealthcheck `yaml:"healthcheck"`
+}
+
+type composeVolume struct {
+	Name     string `yaml:"name"`
+	External bool   `yaml:"external"`
+}
+
+type composeHealthcheck struct {
+	Test     Command `yaml:"test"`
+	Interval string  `yaml:"interval"`
+	Timeout  string  `yaml:"timeout"`
+	Retries  int     `yaml:"retries"`
+	Disable  bool    `yaml:"disable"`
+}
+
+// composeEnvironment is a service's environment variables, which can be
+// written as either a map or a list of "NAME=value". Variables without a
+// value are taken from the shell envctl runs in.
+type composeEnvironment map[string]string
+
+// composeDependencies are the services a service depends on, which can be
+// written as either a list or a map of names to conditions. Every condition
+// is treated the same: the service has to be healthy.
+type composeDependencies []string
+
+var projectChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// composeVariable matches a value that's nothing but a variable, like
+// "${PGPASSWORD}" or "$PGPASSWORD".
+var composeVariable = regexp.MustCompile(
+	`^\$(?:([a-zA-Z_][a-zA-Z0-9_]*)|\{([a-zA-Z_][a-zA-Z0-9_]*)\})$`)
+
+// composeName matches the name of a variable at the start of a string.
+var composeName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+
+// LoadCompose reads the services from a docker-compose file. Bind mounts are
+// made relative to the file's directory, and named volumes are prefixed with
+// the project name, the same as docker-compose does, so that the services
+// pick up the data they had when they were run by docker-compose.
+func LoadCompose(file string) (map[string]Service, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var cf composeFile
+	if err := yaml.Unmarshal(raw, &cf); err != nil {
+		return nil, err
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+
+	project := cf.Name
+	if project == "" {
+		project = projectChars.ReplaceAllString(
+			strings.ToLower(filepath.Base(dir)), "")
+	}
+
+	svcs := map[string]Service{}
+	for _, name := range sortedKeys(cf.Services) {
+		if !objectName.MatchString(name) {
+			return nil, fmt.Errorf("invalid service name %q", name)
+		}
+
+		svc, err := cf.Services[name].service(cf, project, dir)
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %v", name, err)
+		}
+
+		svcs[name] = svc
+	}
+
+	return svcs, nil
+}
+
+// service converts the compose service into an envctl one, checking it along
+// the way.
+func (cs composeService) service(
+	cf composeFile,
+	project string,
+	dir string,
+) (Service, error) {
+	if cs.Image == "" && cs.Build != nil {
+		return Service{}, errors.New(
+			"only services with an image are supported, not ones with a build")
+	}
+
+	if err := cs.interpolate(); err != nil {
+		return Service{}, err
+	}
+
+	if cs.Image == "" {
+		return Service{}, errors.New("missing image")
+	}
+
+	if _, err := cs.Ports.Bindings(); err != nil {
+		return Service{}, err
+	}
+
+	vars, err := cs.Environment.variables()
+	if err != nil {
+		return Service{}, err
+	}
+
+	svc := Service{
+		Image:     cs.Image,
+		Variables: vars,
+		Ports:     cs.Ports,
+		DependsOn: []string(cs.DependsOn),
+	}
+
+	for _, spec := range cs.Volumes {
+		vol, err := ParseVolume(spec)
+		if err != nil {
+			return Service{}, err
+		}
+
+		switch {
+		case vol.Type == "bind" && strings.HasPrefix(vol.Source, "."):
+			vol.Source = filepath.Join(dir, vol.Source)
+		case vol.Type == "volume" && vol.Source != "":
+			cv, ok := cf.Volumes[vol.Source]
+			if !ok {
+				return Service{}, fmt.Errorf("volume %v isn't declared", vol.Source)
+			}
+
+			switch {
+			case cv != nil && cv.Name != "":
+				vol.Source = cv.Name
+			case cv == nil || !cv.External:
+				vol.Source = project + "_" + vol.Source
+			}
+		}
+
+		spec = vol.Target
+		if vol.Source != "" {
+			spec = vol.Source + ":" + spec
+		}
+
+		if vol.ReadOnly {
+			spec += ":ro"
+		}
+
+		svc.Volumes = append(svc.Volumes, spec)
+	}
+
+	// A healthcheck without a test only changes the timing of the image's
+	// own, so the image's is used as it is.
+	if hc := cs.Healthcheck; hc != nil && (len(hc.Test) > 0 || hc.Disable) {
+		svc.Healthcheck = &Healthcheck{
+			Test:     hc.Test,
+			Interval: hc.Interval,
+			Timeout:  hc.Timeout,
+			Retries:  hc.Retries,
+		}
+
+		if hc.Disable {
+			svc.Healthcheck.Test = Command{"NONE"}
+		}
+
+		if _, err := svc.Healthcheck.Parse(); err != nil {
+			return Service{}, err
+		}
+	}
+
+	return svc, nil
+}
+
+// interpolate interpolates the variables in the service's image, ports,
+// volumes and healthcheck. Its environment is interpolated separately, since
+// some of it is left for when the environment is created.
+func (cs *composeService) interpolate() error {
+	var err error
+
+	if cs.Image, err = interpolate(cs.Image); err != nil {
+		return fmt.Errorf("image: %v", err)
+	}
+
+	ports, err := interpolateAll(cs.Ports)
+	if err != nil {
+		return fmt.Errorf("ports: %v", err)
+	}
+
+	cs.Ports = Ports(ports)
+
+	if cs.Volumes, err = interpolateAll(cs.Volumes); err != nil {
+		return fmt.Errorf("volumes: %v", err)
+	}
+
+	if cs.Healthcheck == nil {
+		return nil
+	}
+
+	hc := *cs.Healthcheck
+	cs.Healthcheck = &hc
+
+	test, err := interpolateAll(hc.Test)
+	if err != nil {
+		return fmt.Errorf("healthcheck: %v", err)
+	}
+
+	hc.Test = Command(test)
+
+	for _, s := range []*string{&hc.Interval, &hc.Timeout} {
+		if *s, err = interpolate(*s); err != nil {
+			return fmt.Errorf("healthcheck: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalYAML reads the environment variables from either of their forms.
+func (e *composeEnvironment) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	vars := map[string]*string{}
+
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		for _, kv := range list {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) == 1 {
+				vars[parts[0]] = nil
+			} else {
+				vars[parts[0]] = &parts[1]
+			}
+		}
+	} else if err := unmarshal(&vars); err != nil {
+		return err
+	}
+
+	*e = composeEnvironment{}
+	for k, v := range vars {
+		if v == nil {
+			(*e)[k] = "$" + k
+		} else {
+			(*e)[k] = *v
+		}
+	}
+
+	return nil
+}
+
+// variables returns the environment variables as envctl variables. A value
+// that's nothing but a variable is left for envctl to evaluate when the
+// environment is created, like the ones in the config file, so that a missing
+// one is an error. Anything else is interpolated right away.
+func (e composeEnvironment) variables() (map[string]string, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	vars := map[string]string{}
+
+	for _, k := range sortedKeys(map[string]string(e)) {
+		v := e[k]
+
+		if m := composeVariable.FindStringSubmatch(v); m != nil {
+			vars[k] = "$" + m[1] + m[2]
+			continue
+		}
+
+		val, err := interpolate(v)
+		if err != nil {
+			return nil, fmt.Errorf("variable %v: %v", k, err)
+		}
+
+		// envctl would take the value for a variable of its own.
+		if strings.HasPrefix(val, "$") {
+			return nil, fmt.Errorf("variable %v: values can't start with a literal $", k)
+		}
+
+		vars[k] = val
+	}
+
+	return vars, nil
+}
+
+// interpolate replaces the variables in s with their values from the shell
+// envctl runs in, the way docker-compose does. "$NAME" and "${NAME}" are
+// replaced by the variable's value, or nothing if it isn't set, and "$$" by
+// "$". Inside of braces, "NAME:-default" and "NAME-default" fall back on the
+// default when the variable is empty or unset, "NAME:+alt" and "NAME+alt" are
+// replaced by alt when it isn't, and "NAME:?err" and "NAME?err" fail with err
+// when it is.
+func interpolate(s string) (string, error) {
+	out := &strings.Builder{}
+
+	for {
+		i := strings.Index(s, "$")
+		if i == -1 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+
+		out.WriteString(s[:i])
+		s = s[i+1:]
+
+		switch {
+		case strings.HasPrefix(s, "$"):
+			out.WriteString("$")
+			s = s[1:]
+		case strings.HasPrefix(s, "{"):
+			end := strings.Index(s, "}")
+			if end == -1 {
+				return "", fmt.Errorf("unclosed ${ in %q", "$"+s)
+			}
+
+			val, err := expand(s[1:end])
+			if err != nil {
+				return "", err
+			}
+
+			out.WriteString(val)
+			s = s[end+1:]
+		default:
+			name := composeName.FindString(s)
+			if name == "" {
+				return "", fmt.Errorf(
+					"invalid interpolation format in %q, write $$ for a literal $",
+					"$"+s)
+			}
+
+			out.WriteString(os.Getenv(name))
+			s = s[len(name):]
+		}
+	}
+}
+
+// interpolateAll interpolates each of ss, returning nil for nil.
+func interpolateAll(ss []string) ([]string, error) {
+	if ss == nil {
+		return nil, nil
+	}
+
+	out := make([]string, len(ss))
+	for i, s := range ss {
+		val, err := interpolate(s)
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = val
+	}
+
+	return out, nil
+}
+
+// expand returns the value of a variable written in braces, along with its
+// modifier, if it has one.
+func expand(expr string) (string, error) {
+	name := composeName.FindString(expr)
+	if name == "" {
+		return "", fmt.Errorf("invalid variable name in \"${%v}\"", expr)
+	}
+
+	val, set := os.LookupEnv(name)
+	mod := expr[len(name):]
+
+	// With a colon, an empty variable is treated the same as an unset one.
+	unset := !set
+	if strings.HasPrefix(mod, ":") {
+		unset = val == ""
+		mod = mod[1:]
+	}
+
+	if mod == "" && len(expr) == len(name) {
+		return val, nil
+	}
+
+	if mod == "" {
+		return "", fmt.Errorf("invalid interpolation format in \"${%v}\"", expr)
+	}
+
+	switch arg := mod[1:]; mod[0] {
+	case '-':
+		if unset {
+			return arg, nil
+		}
+
+		return val, nil
+	case '+':
+		if unset {
+			return "", nil
+		}
+
+		return arg, nil
+	case '?':
+		if unset {
+			return "", fmt.Errorf("missing variable %v: %v", name, arg)
+		}
+
+		return val, nil
+	}
+
+	return "", fmt.Errorf("invalid interpolation format in \"${%v}\"", expr)
+}
+
+// UnmarshalYAML reads the dependencies from either of their forms.
+func (d *composeDependencies) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*d = list
+		return nil
+	}
+
+	var conditions map[string]struct {
+		Condition string `yaml:"condition"`
+	}
+
+	if err := unmarshal(&conditions); err != nil {
+		return err
+	}
+
+	// The names are sorted since maps come in no particular order, and the
+	// config's hash depends on it.
+	*d = composeDependencies{}
+	for name := range conditions {
+		*d = append(*d, name)
+	}
+
+	sort.Strings(*d)
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testCompose = `---
+version: "3.7"
+services:
+  db:
+    image: postgres:11
+    environment:
+      POSTGRES_USER: app
+      POSTGRES_PASSWORD: ${PGPASSWORD}
+    ports:
+    - "5432:5432"
+    volumes:
+    - pgdata:/var/lib/postgresql/data
+    - ./init:/docker-entrypoint-initdb.d:ro
+    healthcheck:
+      test: ["CMD", "pg_isready"]
+      interval: 2s
+  cache:
+    image: redis:5
+    environment:
+    - REDIS_PASSWORD
+    - MAXMEMORY=100mb
+    volumes:
+    - shared:/data
+    - /tmp
+    depends_on:
+      db:
+        condition: service_healthy
+    healthcheck:
+      disable: true
+volumes:
+  pgdata:
+  shared:
+    external: true
+`
+
+func writeTestFile(t *test_pkg.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal("writing "+name, nil, err)
+	}
+
+	return path
+}
+
+func TestLoadCompose(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-compose")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	proj := filepath.Join(dir, "My App")
+	if err := os.Mkdir(proj, 0755); err != nil {
+		t.Fatal("creating project dir", nil, err)
+	}
+
+	svcs, err := LoadCompose(writeTestFile(&t, proj, "docker-compose.yml", testCompose))
+	if err != nil {
+		t.Fatal("LoadCompose()", nil, err)
+	}
+
+	db := svcs["db"]
+	if "postgres:11" != db.Image || "$PGPASSWORD" != db.Variables["POSTGRES_PASSWORD"] ||
+		"app" != db.Variables["POSTGRES_USER"] {
+
+		t.Fatal("db service", testCompose, db)
+	}
+
+	if len(db.Ports) != 1 || "5432:5432" != db.Ports[0] {
+		t.Fatal("db ports", []string{"5432:5432"}, db.Ports)
+	}
+
+	expected := "myapp_pgdata:/var/lib/postgresql/data|" +
+		filepath.Join(proj, "init") + ":/docker-entrypoint-initdb.d:ro"
+	if actual := strings.Join(db.Volumes, "|"); expected != actual {
+		t.Fatal("db volumes", expected, actual)
+	}
+
+	if db.Healthcheck == nil || "CMD pg_isready" != strings.Join(db.Healthcheck.Test, " ") ||
+		"2s" != db.Healthcheck.Interval {
+
+		t.Fatal("db healthcheck", "CMD pg_isready every 2s", db.Healthcheck)
+	}
+
+	cache := svcs["cache"]
+	if "$REDIS_PASSWORD" != cache.Variables["REDIS_PASSWORD"] ||
+		"100mb" != cache.Variables["MAXMEMORY"] {
+
+		t.Fatal("cache variables", testCompose, cache.Variables)
+	}
+
+	if expected, actual := "shared:/data|/tmp", strings.Join(cache.Volumes, "|"); expected != actual {
+		t.Fatal("cache volumes", expected, actual)
+	}
+
+	if len(cache.DependsOn) != 1 || "db" != cache.DependsOn[0] {
+		t.Fatal("cache dependencies", []string{"db"}, cache.DependsOn)
+	}
+
+	if cache.Healthcheck == nil || "NONE" != strings.Join(cache.Healthcheck.Test, " ") {
+		t.Fatal("cache healthcheck", "NONE", cache.Healthcheck)
+	}
+}
+
+func TestLoadComposeErrors(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-compose")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string]string{
+		"services:\n  app:\n    build: .\n": "service app: only services with an " +
+			"image are supported, not ones with a build",
+		"services:\n  db:\n    image: postgres\n    volumes:\n    - data:/data\n": "service " +
+			"db: volume data isn't declared",
+		"services:\n  db:\n    image: postgres\n    ports:\n    - 5432/http\n": "service " +
+			"db: invalid port protocol \"http\", must be tcp, udp or sctp",
+		"services:\n  db:\n    image: postgres\n    environment:\n      URL: \"${ENVCTL_TEST_UNSET:?is required}/db\"\n": "service " +
+			"db: variable URL: missing variable ENVCTL_TEST_UNSET: is required",
+		"services:\n  db:\n    image: postgres\n    environment:\n      URL: \"${ENVCTL_TEST_UNSET/db\"\n": "service " +
+			"db: variable URL: unclosed ${ in \"${ENVCTL_TEST_UNSET/db\"",
+		"services:\n  db:\n    image: postgres\n    environment:\n      PRICE: \"5$ each\"\n": "service " +
+			"db: variable PRICE: invalid interpolation format in \"$ each\", write $$ for a literal $",
+	}
+
+	for raw, expected := range tests {
+		_, err := LoadCompose(writeTestFile(&t, dir, "docker-compose.yml", raw))
+		if err == nil || expected != err.Error() {
+			t.Fatal("LoadCompose() error for "+raw, expected, err)
+		}
+	}
+}
+
+func TestLoadComposeInterpolation(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-compose")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("ENVCTL_TEST_HOST", "db.local")
+	os.Setenv("ENVCTL_TEST_EMPTY", "")
+	defer os.Unsetenv("ENVCTL_TEST_HOST")
+	defer os.Unsetenv("ENVCTL_TEST_EMPTY")
+
+	svcs, err := LoadCompose(writeTestFile(&t, dir, "docker-compose.yml", `---
+services:
+  app:
+    image: app:${ENVCTL_TEST_UNSET:-1.0}
+    ports:
+    - "${ENVCTL_TEST_UNSET:-8080}:80"
+    volumes:
+    - ${ENVCTL_TEST_UNSET:-./data}:/data
+    healthcheck:
+      test: curl -f http://$ENVCTL_TEST_HOST/ && test -n "$$HOME"
+    environment:
+      PASSWORD: $PGPASSWORD
+      URL: postgres://${ENVCTL_TEST_HOST}:5432/$ENVCTL_TEST_HOST
+      PORT: ${ENVCTL_TEST_UNSET:-5432}
+      EMPTY_DEFAULT: ${ENVCTL_TEST_EMPTY-none}
+      EMPTY_COLON_DEFAULT: ${ENVCTL_TEST_EMPTY:-none}
+      ALT: ${ENVCTL_TEST_HOST:+set}
+      PRICE: 5$$
+      UNSET: x${ENVCTL_TEST_UNSET}
+`))
+	if err != nil {
+		t.Fatal("LoadCompose()", nil, err)
+	}
+
+	expected := map[string]string{
+		"PASSWORD":            "$PGPASSWORD",
+		"URL":                 "postgres://db.local:5432/db.local",
+		"PORT":                "5432",
+		"EMPTY_DEFAULT":       "",
+		"EMPTY_COLON_DEFAULT": "none",
+		"ALT":                 "set",
+		"PRICE":               "5$",
+		"UNSET":               "x",
+	}
+
+	app := svcs["app"]
+	for k, v := range expected {
+		if v != app.Variables[k] {
+			t.Fatal("variable "+k, v, app.Variables[k])
+		}
+	}
+
+	if "app:1.0" != app.Image {
+		t.Fatal("image", "app:1.0", app.Image)
+	}
+
+	if len(app.Ports) != 1 || "8080:80" != app.Ports[0] {
+		t.Fatal("ports", []string{"8080:80"}, app.Ports)
+	}
+
+	if expected := filepath.Join(dir, "data") + ":/data"; len(app.Volumes) != 1 || expected != app.Volumes[0] {
+		t.Fatal("volumes", expected, app.Volumes)
+	}
+
+	expectedTest := `CMD-SHELL curl -f http://db.local/ && test -n "$HOME"`
+	if app.Healthcheck == nil || expectedTest != strings.Join(app.Healthcheck.Test, " ") {
+		t.Fatal("healthcheck", expectedTest, app.Healthcheck)
+	}
+}
+
+func TestComposeDependencyOrder(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	var cs composeService
+	raw := "depends_on:\n  queue:\n    condition: service_started\n" +
+		"  cache:\n    condition: service_healthy\n  db:\n    condition: service_healthy\n"
+
+	// Maps come back in random order, so it takes a few tries to tell.
+	for i := 0; i < 10; i++ {
+		if err := yaml.Unmarshal([]byte(raw), &cs); err != nil {
+			t.Fatal("unmarshaling", nil, err)
+		}
+
+		if expected, actual := "cache db queue", strings.Join(cs.DependsOn, " "); expected != actual {
+			t.Fatal("dependencies", expected, actual)
+		}
+	}
+}
+
+func TestLoadWithCompose(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-compose")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(&t, dir, "docker-compose.yml", testCompose)
+
+	cfg, err := YAML{Path: writeTestFile(&t, dir, "envctl.yaml", `---
+image: ubuntu:latest
+shell: /bin/bash
+compose: docker-compose.yml
+services:
+  queue:
+    image: rabbitmq:3
+    depends_on:
+    - cache
+`)}.Load()
+	if err != nil {
+		t.Fatal("Load()", nil, err)
+	}
+
+	order, err := ServiceOrder(cfg.Services)
+	if err != nil {
+		t.Fatal("ServiceOrder()", nil, err)
+	}
+
+	if expected, actual := "db cache queue", strings.Join(order, " "); expected != actual {
+		t.Fatal("service order", expected, actual)
+	}
+
+	_, err = YAML{Path: writeTestFile(&t, dir, "envctl.yaml", `---
+image: ubuntu:latest
+shell: /bin/bash
+compose: docker-compose.yml
+services:
+  db:
+    image: postgres:12
+`)}.Load()
+
+	expected := "service db is in both the config file and docker-compose.yml"
+	if err == nil || expected != err.Error() {
+		t.Fatal("Load() error", expected, err)
+	}
+}
@@ -2,7 +2,19 @@ package config
 
 // Opts is what tells envctl what the environment looks like.
 type Opts struct {
-	Image string `yaml:"image"`
+	// Runtime is the container runtime the environment runs on, like
+	// "docker" or "podman". It defaults to "docker".
+	Runtime string `yaml:"runtime,omitempty"`
+	// RuntimeOptions are handed to the runtime when its controller is
+	// created. Which ones are understood depends on the runtime.
+	RuntimeOptions map[string]string `yaml:"runtime_options,omitempty"`
+	// Docker is how to connect to the Docker daemon, when that's the runtime.
+	Docker Docker `yaml:"docker,omitempty"`
+
+	Image string `yaml:"image,omitempty"`
+	// Build is an alternative to Image, for building the base image from a
+	// Dockerfile.
+	Build *Build `yaml:"build,omitempty"`
 	// The default for this field is true, so `nil`` needs to be discernable
 	// from the default `false` value.
 	CacheImage *bool `yaml:"cache_image,omitempty"`
@@ -10,14 +22,45 @@ type Opts struct {
 	User      string            `yaml:"user"`
 	Shell     string            `yaml:"shell"`
 	Mount     string            `yaml:"mount,omitempty"`
+	Mounts    []Mount           `yaml:"mounts,omitempty"`
 	Variables map[string]string `yaml:"variables,omitempty"`
 	Bootstrap []string          `yaml:"bootstrap,omitempty"`
+	// BakeBootstrap runs the bootstrap steps while building the image instead
+	// of after creating the container, so that they're cached as image
+	// layers. The repo isn't mounted yet at that point, so the steps can't
+	// depend on anything in it.
+	BakeBootstrap bool `yaml:"bake_bootstrap,omitempty"`
+	// Caches are paths in the container that each get a volume of their
+	// own. The volumes belong to the project rather than the environment, so
+	// they're kept when it's destroyed, and the next one picks up where it
+	// left off.
+	Caches []string `yaml:"caches,omitempty"`
 
 	// Exposing the host network isn't a cross-platform solution, so the
 	// upfront requirement is to expose any ports that the user needs. The ports
 	// are to be mapped directly from container to host so that whatever is
 	// exposed in the container is the port that's accessed on the host.
-	Ports L3Ports `yaml:"ports,omitempty"`
+	Ports Ports `yaml:"ports,omitempty"`
+
+	// Resources limit what the environment can use, so that a runaway
+	// process in it can't take over the host.
+	Resources Resources `yaml:"resources,omitempty"`
+
+	// Services run alongside the environment, like databases. They're keyed
+	// by name, which is also the hostname the environment reaches them at.
+	Services map[string]Service `yaml:"services,omitempty"`
+	// Compose is a docker-compose file to take more services from, relative
+	// to the config file's directory.
+	Compose string `yaml:"compose,omitempty"`
+
+	// Network is how the environment can be reached by its services, and by
+	// other containers.
+	Network Network `yaml:"network,omitempty"`
+
+	// Ready is how to tell that the environment is ready for use, once
+	// bootstrap has, say, started a dev server. "create" doesn't report the
+	// environment as ready until it passes.
+	Ready *Ready `yaml:"ready,omitempty"`
 }
 
 // Loader is anything that can load a configuration file.
@@ -25,5 +68,122 @@ type Loader interface {
 	Load() (Opts, error)
 }
 
-// L3Ports are mappings between a layer 3 protocol like TCP and a port number.
-type L3Ports map[string][]int
+// Build describes how to build an image from a Dockerfile.
+type Build struct {
+	// Context is the directory sent to the builder, relative to the config
+	// file's directory. It defaults to that directory.
+	Context string `yaml:"context,omitempty"`
+	// Dockerfile is the path to the Dockerfile, relative to Context. It
+	// defaults to "Dockerfile".
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+	// Target is the stage to build in a multi-stage Dockerfile.
+	Target string `yaml:"target,omitempty"`
+}
+
+// Mount is something to mount in the container besides the repo.
+type Mount struct {
+	// Type is "bind" for a directory or file from the host, "volume" for a
+	// named volume that outlives the container, or "tmpfs" for an in-memory
+	// filesystem. It defaults to "bind".
+	Type string `yaml:"type,omitempty"`
+	// Source is the path on the host for bind mounts, relative to the config
+	// file's directory or starting with "~", and the name of the volume for
+	// volumes. Tmpfs mounts don't have one.
+	Source   string `yaml:"source,omitempty"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only,omitempty"`
+}
+
+// Resources are limits on what the environment can use. Anything left out
+// isn't limited.
+type Resources struct {
+	// CPUs is how many CPUs the environment can use, like 1.5.
+	CPUs float64 `yaml:"cpus,omitempty"`
+	// Memory is how much memory the environment can use, like "2g".
+	Memory string `yaml:"memory,omitempty"`
+	// PidsLimit is how many processes can run in the environment at once.
+	PidsLimit int64 `yaml:"pids_limit,omitempty"`
+	// ShmSize is the size of /dev/shm, like "256m".
+	ShmSize string `yaml:"shm_size,omitempty"`
+}
+
+// Network is how the environment is attached to its network, which its
+// services are on as well.
+type Network struct {
+	// Hostname is the environment's hostname. Its services can reach it at
+	// the hostname too.
+	Hostname string `yaml:"hostname,omitempty"`
+	// Aliases are other names the environment can be reached at.
+	Aliases []string `yaml:"aliases,omitempty"`
+	// External are networks that already exist, like one created by a
+	// docker-compose stack, for the environment to join as well. The
+	// environment has the same hostname and aliases on them.
+	External []string `yaml:"external,omitempty"`
+}
+
+// Ready is a probe for whether the environment is ready for use. Only one of
+// Command, Port and URL can be set.
+type Ready struct {
+	// Command is run with the environment's shell, and passes when it exits
+	// with status 0.
+	Command string `yaml:"command,omitempty"`
+	// Port is a TCP port in the environment, which passes once something
+	// listens on it. It has to be published.
+	Port int `yaml:"port,omitempty"`
+	// URL is an HTTP URL in the environment, like
+	// "http://localhost:3000/health", which passes once it responds without an
+	// error. Its port has to be published.
+	URL string `yaml:"url,omitempty"`
+	// Timeout is how long the probe gets to pass, like "5m". It defaults to
+	// 2m.
+	Timeout string `yaml:"timeout,omitempty"`
+	// Interval is the time between attempts, like "2s". It defaults to 1s.
+	Interval string `yaml:"interval,omitempty"`
+}
+
+// Service is a container that runs alongside the environment.
+type Service struct {
+	Image     string            `yaml:"image"`
+	Variables map[string]string `yaml:"variables,omitempty"`
+	// Ports are the service's ports to publish on the host, in the same
+	// format as the environment's. The environment itself can reach every
+	// port of the service without publishing it.
+	Ports Ports `yaml:"ports,omitempty"`
+	// Volumes are mounted in the service, each written like
+	// "source:target[:ro]", the same as in a docker-compose file. Sources
+	// that are paths are bind mounted, and anything else is a named volume.
+	Volumes     []string     `yaml:"volumes,omitempty"`
+	Healthcheck *Healthcheck `yaml:"healthcheck,omitempty"`
+	// DependsOn are the services that have to be healthy before this one is
+	// started.
+	DependsOn []string `yaml:"depends_on,omitempty"`
+}
+
+// Healthcheck is how to tell that a service is ready for use. The environment
+// isn't created until it is.
+type Healthcheck struct {
+	// Test is the command that checks the service, either as a list of
+	// arguments or as a string run by the service's shell.
+	Test Command `yaml:"test"`
+	// Interval is the time between checks, like "5s".
+	Interval string `yaml:"interval,omitempty"`
+	// Timeout is how long a check can take before it counts as failed.
+	Timeout string `yaml:"timeout,omitempty"`
+	// Retries is how many checks in a row have to fail for the service to be
+	// unhealthy.
+	Retries int `yaml:"retries,omitempty"`
+}
+
+// Docker is how to connect to the Docker daemon. Anything left empty comes
+// from the DOCKER_* environment variables, like with the docker CLI.
+type Docker struct {
+	// Host is the daemon socket, like "tcp://build-host:2376".
+	Host string `yaml:"host,omitempty"`
+	// CertPath is the directory with the TLS certificates for the daemon,
+	// relative to the config file's directory.
+	CertPath   string `yaml:"cert_path,omitempty"`
+	APIVersion string `yaml:"api_version,omitempty"`
+	// Context is the name of a context created with "docker context create".
+	Context string `yaml:"context,omitempty"`
+}
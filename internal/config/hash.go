@@ -0,0 +1,54 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Hash returns a hash of everything in cfg that goes into creating an
+// environment, so that an environment created from an older version of the
+// config file can be told apart from one created from the current one. Files
+// in dir that bootstrap steps refer to, like "scripts/setup.sh", are part of
+// it too, since changing them changes what bootstrap does.
+func Hash(cfg Opts, dir string) (string, error) {
+	// How to connect to the Docker daemon doesn't change the environment.
+	cfg.Docker = Docker{}
+
+	buf, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(buf)
+
+	for _, step := range cfg.Bootstrap {
+		for _, field := range strings.Fields(step) {
+			if filepath.IsAbs(field) {
+				continue
+			}
+
+			path := filepath.Join(dir, field)
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(h, "\x00%v\x00", field)
+			h.Write(contents)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestHash(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-hash")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeTestFile(&t, dir, "setup.sh", "npm install\n")
+
+	cfg := Opts{
+		Image:     "node:12",
+		Shell:     "/bin/bash",
+		Bootstrap: []string{"bash setup.sh", "echo done"},
+	}
+
+	hash := func(cfg Opts) string {
+		h, err := Hash(cfg, dir)
+		if err != nil {
+			t.Fatal("Hash()", nil, err)
+		}
+
+		return h
+	}
+
+	original := hash(cfg)
+
+	// How to reach the Docker daemon doesn't change the environment.
+	cfg.Docker.Host = "tcp://build-host:2376"
+	if actual := hash(cfg); original != actual {
+		t.Fatal("hash with another docker host", original, actual)
+	}
+
+	writeTestFile(&t, dir, "setup.sh", "npm ci\n")
+	if actual := hash(cfg); original == actual {
+		t.Fatal("hash with another bootstrap script", "a new hash", actual)
+	}
+
+	writeTestFile(&t, dir, "setup.sh", "npm install\n")
+	cfg.Ports = Ports{"3000"}
+	if actual := hash(cfg); original == actual {
+		t.Fatal("hash with another port", "a new hash", actual)
+	}
+}
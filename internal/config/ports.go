@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// Ports are the container's ports to publish on the host, each written like
+// "[host_ip:][host_port:]container_port[/protocol]". Ports can be ranges,
+// like "3000-3005", and a host port of "auto" lets the container engine pick
+// a free one, as does leaving it empty, like in ":3000". Without a host port,
+// the port is published on the same port on the host.
+//
+// The older form, a map of protocols to lists of ports, is still accepted:
+//
+//	ports:
+//	  tcp:
+//	  - 4567
+type Ports []string
+
+// L3Ports are mappings between a layer 3 protocol like TCP and a port number.
+// It's the older form of Ports.
+type L3Ports map[string][]int
+
+// UnmarshalYAML reads Ports from either of its forms. Ports in the older form
+// come out sorted by protocol, so that they're in the same order every time.
+func (p *Ports) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var specs []string
+	if err := unmarshal(&specs); err == nil {
+		*p = specs
+		return nil
+	}
+
+	var l3 L3Ports
+	if err := unmarshal(&l3); err != nil {
+		return err
+	}
+
+	*p = Ports{}
+	for _, proto := range sortedKeys(l3) {
+		for _, port := range l3[proto] {
+			*p = append(*p, fmt.Sprintf("%v/%v", port, proto))
+		}
+	}
+
+	return nil
+}
+
+// Bindings parses every port into the bindings to create for it.
+func (p Ports) Bindings() ([]container.PortBinding, error) {
+	bindings := []container.PortBinding{}
+
+	for _, spec := range p {
+		b, err := ParsePort(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		bindings = append(bindings, b...)
+	}
+
+	return bindings, nil
+}
+
+// ParsePort parses a port in the format of Ports. It returns a binding for
+// every port in a range.
+func ParsePort(spec string) ([]container.PortBinding, error) {
+	rest, proto := spec, "tcp"
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		rest, proto = rest[:i], rest[i+1:]
+	}
+
+	if !protocols[proto] {
+		return nil, fmt.Errorf(
+			"invalid port protocol %q, must be tcp, udp or sctp", proto)
+	}
+
+	var ip, host string
+	cport := rest
+	i := strings.LastIndex(rest, ":")
+	if i >= 0 {
+		// An empty host port means the same thing as "auto", like with the
+		// docker CLI.
+		cport, host = rest[i+1:], rest[:i]
+		if host == "" || strings.HasSuffix(host, ":") {
+			host += "auto"
+		}
+
+		if j := strings.LastIndex(host, ":"); j >= 0 {
+			ip, host = host[:j], host[j+1:]
+			ip = strings.TrimSuffix(strings.TrimPrefix(ip, "["), "]")
+
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("invalid host IP %q", ip)
+			}
+		}
+	}
+
+	cfirst, clast, err := parsePortRange(cport)
+	if err != nil {
+		return nil, err
+	}
+
+	var hfirst, hlast int
+	switch host {
+	case "auto":
+	case "":
+		hfirst, hlast = cfirst, clast
+	default:
+		hfirst, hlast, err = parsePortRange(host)
+		if err != nil {
+			return nil, err
+		}
+
+		if hlast-hfirst != clast-cfirst {
+			return nil, fmt.Errorf(
+				"host ports %v and container ports %v must be the same size",
+				host, cport)
+		}
+	}
+
+	bindings := []container.PortBinding{}
+	for i := 0; i <= clast-cfirst; i++ {
+		b := container.PortBinding{
+			HostIP:        ip,
+			ContainerPort: cfirst + i,
+			Protocol:      proto,
+		}
+
+		if hfirst != 0 {
+			b.HostPort = hfirst + i
+		}
+
+		bindings = append(bindings, b)
+	}
+
+	return bindings, nil
+}
+
+// parsePortRange parses a port, or a range of them like "3000-3005", and
+// returns the first and last port in it.
+func parsePortRange(raw string) (int, int, error) {
+	first, last := raw, raw
+	if i := strings.Index(raw, "-"); i >= 0 {
+		first, last = raw[:i], raw[i+1:]
+	}
+
+	f, err := parsePort(first)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	l, err := parsePort(last)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if l < f {
+		return 0, 0, fmt.Errorf("invalid port range %q", raw)
+	}
+
+	return f, l, nil
+}
+
+func parsePort(raw string) (int, error) {
+	p, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid port %q", raw)
+	}
+
+	if p < 1 || p > 65535 {
+		return 0, fmt.Errorf(
+			"port %v is out of range, must be between 1 and 65535", p)
+	}
+
+	return p, nil
+}
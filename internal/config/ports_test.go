@@ -0,0 +1,136 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestParsePort(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	tests := []struct {
+		spec     string
+		expected []container.PortBinding
+	}{
+		{"3000", []container.PortBinding{
+			{HostPort: 3000, ContainerPort: 3000, Protocol: "tcp"},
+		}},
+		{"8080:80/udp", []container.PortBinding{
+			{HostPort: 8080, ContainerPort: 80, Protocol: "udp"},
+		}},
+		{"127.0.0.1:8080:80", []container.PortBinding{
+			{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		}},
+		{"[::1]:8080:80", []container.PortBinding{
+			{HostIP: "::1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		}},
+		{"auto:3000", []container.PortBinding{
+			{ContainerPort: 3000, Protocol: "tcp"},
+		}},
+		{"127.0.0.1::3000", []container.PortBinding{
+			{HostIP: "127.0.0.1", ContainerPort: 3000, Protocol: "tcp"},
+		}},
+		{"4000-4001:3000-3001", []container.PortBinding{
+			{HostPort: 4000, ContainerPort: 3000, Protocol: "tcp"},
+			{HostPort: 4001, ContainerPort: 3001, Protocol: "tcp"},
+		}},
+		{"auto:3000-3001/sctp", []container.PortBinding{
+			{ContainerPort: 3000, Protocol: "sctp"},
+			{ContainerPort: 3001, Protocol: "sctp"},
+		}},
+	}
+
+	for _, test := range tests {
+		actual, err := ParsePort(test.spec)
+		if err != nil {
+			t.Fatal("parsing "+test.spec, nil, err)
+		}
+
+		if len(test.expected) != len(actual) {
+			t.Fatal("parsing "+test.spec, test.expected, actual)
+		}
+
+		for i := range test.expected {
+			if test.expected[i] != actual[i] {
+				t.Fatal("parsing "+test.spec, test.expected, actual)
+			}
+		}
+	}
+}
+
+func TestParsePortErrors(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	tests := map[string]string{
+		"80/http":         `invalid port protocol "http", must be tcp, udp or sctp`,
+		"70000":           "port 70000 is out of range, must be between 1 and 65535",
+		"web":             `invalid port "web"`,
+		"localhost:80:80": `invalid host IP "localhost"`,
+		"3001-3000":       `invalid port range "3001-3000"`,
+		"4000:3000-3001": "host ports 4000 and container ports 3000-3001 " +
+			"must be the same size",
+	}
+
+	for spec, expected := range tests {
+		_, err := ParsePort(spec)
+		if err == nil || expected != err.Error() {
+			t.Fatal("parsing "+spec, expected, err)
+		}
+	}
+}
+
+func TestUnmarshalPorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	tests := map[string]Ports{
+		"ports: [3000, 127.0.0.1:8080:80]":      {"3000", "127.0.0.1:8080:80"},
+		"ports: {udp: [53], tcp: [4567, 4568]}": {"4567/tcp", "4568/tcp", "53/udp"},
+	}
+
+	for raw, expected := range tests {
+		var cfg Opts
+		if err := yaml.UnmarshalStrict([]byte(raw), &cfg); err != nil {
+			t.Fatal("unmarshaling "+raw, nil, err)
+		}
+
+		if len(expected) != len(cfg.Ports) {
+			t.Fatal("unmarshaling "+raw, expected, cfg.Ports)
+		}
+
+		for i := range expected {
+			if expected[i] != cfg.Ports[i] {
+				t.Fatal("unmarshaling "+raw, expected, cfg.Ports)
+			}
+		}
+	}
+}
+
+func TestValidatePorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+ports:
+- 3000
+- 127.0.0.1:auto:8080
+- 0.0.0.1.2:80:80
+- 3000-3002:4000
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 7: invalid host IP \"0.0.0.1.2\"\n" +
+		"line 8: host ports 3000-3002 and container ports 4000 must be the same size"
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+const (
+	defaultReadyTimeout  = 2 * time.Minute
+	defaultReadyInterval = time.Second
+)
+
+// Probe converts the ready block into the probe to create the container with.
+func (r Ready) Probe() (container.Probe, error) {
+	set := 0
+	for _, ok := range []bool{r.Command != "", r.Port != 0, r.URL != ""} {
+		if ok {
+			set++
+		}
+	}
+
+	switch {
+	case set == 0:
+		return container.Probe{}, errors.New(
+			"ready is missing a command, port or url")
+	case set > 1:
+		return container.Probe{}, errors.New(
+			"only one of command, port or url can be set for ready")
+	}
+
+	if r.Port < 0 || r.Port > 65535 {
+		return container.Probe{}, fmt.Errorf("invalid port %v", r.Port)
+	}
+
+	if r.URL != "" {
+		if _, err := URLPort(r.URL); err != nil {
+			return container.Probe{}, err
+		}
+	}
+
+	timeout, err := parseDuration("timeout", r.Timeout)
+	if err != nil {
+		return container.Probe{}, err
+	}
+
+	interval, err := parseDuration("interval", r.Interval)
+	if err != nil {
+		return container.Probe{}, err
+	}
+
+	if timeout == 0 {
+		timeout = defaultReadyTimeout
+	}
+
+	if interval == 0 {
+		interval = defaultReadyInterval
+	}
+
+	return container.Probe{
+		Command:  r.Command,
+		Port:     r.Port,
+		URL:      r.URL,
+		Timeout:  timeout,
+		Interval: interval,
+	}, nil
+}
+
+// URLPort returns the port of an HTTP URL in the environment, which has to be
+// on localhost, since that's the only host that's the same on both sides of a
+// published port.
+func URLPort(raw string) (int, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return 0, fmt.Errorf("invalid url %q, must be an http or https url", raw)
+	}
+
+	if host := u.Hostname(); host != "localhost" && host != "127.0.0.1" {
+		return 0, fmt.Errorf("url %q must be on localhost", raw)
+	}
+
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			return 443, nil
+		}
+
+		return 80, nil
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return 0, fmt.Errorf("invalid url %q, must be an http or https url", raw)
+	}
+
+	return port, nil
+}
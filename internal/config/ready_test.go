@@ -0,0 +1,95 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestReadyProbe(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	actual, err := Ready{URL: "http://localhost:3000/health", Interval: "2s"}.Probe()
+	if err != nil {
+		t.Fatal("Probe()", nil, err)
+	}
+
+	expected := container.Probe{
+		URL:      "http://localhost:3000/health",
+		Timeout:  2 * time.Minute,
+		Interval: 2 * time.Second,
+	}
+	if expected != actual {
+		t.Fatal("probe", expected, actual)
+	}
+
+	tests := map[string]Ready{
+		"ready is missing a command, port or url":               {Timeout: "1m"},
+		"only one of command, port or url can be set for ready": {Command: "true", Port: 80},
+		`url "http://db:5432" must be on localhost`:             {URL: "http://db:5432"},
+		`invalid url "ftp://localhost", must be an http or https url`: {
+			URL: "ftp://localhost",
+		},
+	}
+
+	for expected, r := range tests {
+		_, err := r.Probe()
+		if err == nil || expected != err.Error() {
+			t.Fatal("Probe() error", expected, err)
+		}
+	}
+}
+
+func TestURLPort(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	tests := map[string]int{
+		"http://localhost:3000/health": 3000,
+		"http://127.0.0.1":             80,
+		"https://localhost/":           443,
+	}
+
+	for raw, expected := range tests {
+		actual, err := URLPort(raw)
+		if err != nil {
+			t.Fatal("URLPort("+raw+")", nil, err)
+		}
+
+		if expected != actual {
+			t.Fatal("port for "+raw, expected, actual)
+		}
+	}
+}
+
+func TestValidateReady(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+ports:
+- 3000
+- 8080/udp
+ready:
+  port: 8080
+  url: http://localhost:3000/health
+  timeout: forever
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 7: only one of command, port or url can be set for ready\n" +
+		"line 8: port 8080 isn't published, add it to ports\n" +
+		"line 10: invalid timeout \"forever\", must be a duration like 5s or 1m"
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
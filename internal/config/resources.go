@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	units "github.com/docker/go-units"
+)
+
+// Limits converts the resources into the limits to create the container with.
+func (r Resources) Limits() (container.Resources, error) {
+	if r.CPUs < 0 {
+		return container.Resources{}, fmt.Errorf(
+			"cpus %v must not be negative", r.CPUs)
+	}
+
+	if r.PidsLimit < 0 {
+		return container.Resources{}, fmt.Errorf(
+			"pids_limit %v must not be negative", r.PidsLimit)
+	}
+
+	memory, err := parseSize("memory", r.Memory)
+	if err != nil {
+		return container.Resources{}, err
+	}
+
+	shm, err := parseSize("shm_size", r.ShmSize)
+	if err != nil {
+		return container.Resources{}, err
+	}
+
+	return container.Resources{
+		NanoCPUs:  int64(r.CPUs * 1e9),
+		Memory:    memory,
+		PidsLimit: r.PidsLimit,
+		ShmSize:   shm,
+	}, nil
+}
+
+// parseSize parses a size like "512m" or "2g" into bytes. An empty size is 0.
+func parseSize(field string, raw string) (int64, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	size, err := units.RAMInBytes(raw)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid %v %q, must be a size like 512m or 2g",
+			field, raw)
+	}
+
+	return size, nil
+}
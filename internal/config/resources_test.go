@@ -0,0 +1,34 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestResourceLimits(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	res := Resources{CPUs: 1.5, Memory: "2g", PidsLimit: 512, ShmSize: "256m"}
+
+	expected := container.Resources{
+		NanoCPUs:  1500000000,
+		Memory:    2 * 1024 * 1024 * 1024,
+		PidsLimit: 512,
+		ShmSize:   256 * 1024 * 1024,
+	}
+
+	actual, err := res.Limits()
+	if err != nil {
+		t.Fatal("Limits()", nil, err)
+	}
+
+	if expected != actual {
+		t.Fatal("limits", expected, actual)
+	}
+
+	if _, err := (Resources{ShmSize: "big"}).Limits(); err == nil {
+		t.Fatal("invalid shm_size", "error", nil)
+	}
+}
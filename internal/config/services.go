@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// Command is a command to run in a container, in Docker's form: "CMD"
+// followed by the command's arguments, or "CMD-SHELL" followed by a command
+// for the container's shell. It can be written as either a list of arguments
+// or a string for the shell, and the prefix is filled in.
+type Command []string
+
+// UnmarshalYAML reads a Command from either of its forms. Lists that already
+// start with "CMD" or "CMD-SHELL" are kept as they are, as is "NONE", which
+// turns off a healthcheck the image has.
+func (c *Command) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var shell string
+	if err := unmarshal(&shell); err == nil {
+		*c = Command{"CMD-SHELL", shell}
+		return nil
+	}
+
+	var args []string
+	if err := unmarshal(&args); err != nil {
+		return err
+	}
+
+	if len(args) > 0 &&
+		(args[0] == "CMD" || args[0] == "CMD-SHELL" || args[0] == "NONE") {
+
+		*c = args
+		return nil
+	}
+
+	*c = append(Command{"CMD"}, args...)
+	return nil
+}
+
+// empty reports whether there's nothing to run besides the prefix.
+func (c Command) empty() bool {
+	if len(c) == 1 && c[0] == "NONE" {
+		return false
+	}
+
+	return len(c) < 2 || strings.TrimSpace(strings.Join(c[1:], "")) == ""
+}
+
+// Parse converts the healthcheck into the one to create the service with.
+func (h Healthcheck) Parse() (container.Healthcheck, error) {
+	if h.Test.empty() {
+		return container.Healthcheck{}, errors.New("healthcheck is missing a test")
+	}
+
+	if h.Retries < 0 {
+		return container.Healthcheck{}, fmt.Errorf(
+			"retries %v must not be negative", h.Retries)
+	}
+
+	interval, err := parseDuration("interval", h.Interval)
+	if err != nil {
+		return container.Healthcheck{}, err
+	}
+
+	timeout, err := parseDuration("timeout", h.Timeout)
+	if err != nil {
+		return container.Healthcheck{}, err
+	}
+
+	return container.Healthcheck{
+		Test:     h.Test,
+		Interval: interval,
+		Timeout:  timeout,
+		Retries:  h.Retries,
+	}, nil
+}
+
+// parseDuration parses a duration like "5s" or "1m30s". An empty duration is
+// 0.
+func parseDuration(field string, raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %v %q, must be a duration like 5s or 1m",
+			field, raw)
+	}
+
+	return d, nil
+}
+
+// ParseVolume parses a volume in the format of Service.Volumes. Sources that
+// start with "/", "." or "~" are bind mounted, and anything else is the name
+// of a volume. A volume with just a target gets a new volume of its own, which
+// is removed along with the service.
+func ParseVolume(spec string) (Mount, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 || spec == "" {
+		return Mount{}, fmt.Errorf("invalid volume %q", spec)
+	}
+
+	m := Mount{Type: "volume", Target: parts[len(parts)-1]}
+	if len(parts) > 1 {
+		m.Source, m.Target = parts[0], parts[1]
+	}
+
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			m.ReadOnly = true
+		case "rw":
+		default:
+			return Mount{}, fmt.Errorf("invalid volume mode %q, must be ro or rw",
+				parts[2])
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(m.Source, "/") || strings.HasPrefix(m.Source, ".") ||
+		strings.HasPrefix(m.Source, "~"):
+
+		m.Type = "bind"
+	case m.Source != "" && !volumeName.MatchString(m.Source):
+		return Mount{}, fmt.Errorf("invalid volume name %q", m.Source)
+	}
+
+	if !path.IsAbs(m.Target) {
+		return Mount{}, fmt.Errorf("volume target %q must be an absolute path",
+			m.Target)
+	}
+
+	return m, nil
+}
+
+// ServiceOrder returns the names of the services in the order to start them
+// in, so that every service comes after the ones it depends on. Services
+// that don't depend on each other are sorted by name.
+func ServiceOrder(svcs map[string]Service) ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	order := []string{}
+	state := map[string]int{}
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			for i, p := range path {
+				if p == name {
+					path = path[i:]
+					break
+				}
+			}
+
+			return fmt.Errorf("services depend on each other: %v",
+				strings.Join(append(path, name), " -> "))
+		}
+
+		state[name] = visiting
+
+		deps := append([]string{}, svcs[name].DependsOn...)
+		sort.Strings(deps)
+
+		for _, dep := range deps {
+			if _, ok := svcs[dep]; !ok {
+				return fmt.Errorf("service %v depends on unknown service %v",
+					name, dep)
+			}
+
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range sortedKeys(svcs) {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
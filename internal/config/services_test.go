@@ -0,0 +1,183 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestUnmarshalCommand(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	tests := map[string]string{
+		`pg_isready -U postgres`:           "CMD-SHELL|pg_isready -U postgres",
+		`[redis-cli, ping]`:                "CMD|redis-cli|ping",
+		`[CMD, redis-cli, ping]`:           "CMD|redis-cli|ping",
+		`[CMD-SHELL, "curl -f localhost"]`: "CMD-SHELL|curl -f localhost",
+	}
+
+	for raw, expected := range tests {
+		var cmd Command
+		if err := yaml.Unmarshal([]byte(raw), &cmd); err != nil {
+			t.Fatal("unmarshaling "+raw, nil, err)
+		}
+
+		if actual := strings.Join(cmd, "|"); expected != actual {
+			t.Fatal("command for "+raw, expected, actual)
+		}
+	}
+}
+
+func TestParseHealthcheck(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	hc := Healthcheck{
+		Test:     Command{"CMD", "pg_isready"},
+		Interval: "2s",
+		Timeout:  "1m",
+		Retries:  5,
+	}
+
+	actual, err := hc.Parse()
+	if err != nil {
+		t.Fatal("Parse()", nil, err)
+	}
+
+	if 2*time.Second != actual.Interval || time.Minute != actual.Timeout ||
+		5 != actual.Retries || "CMD pg_isready" != strings.Join(actual.Test, " ") {
+
+		t.Fatal("healthcheck", hc, actual)
+	}
+
+	if _, err := (Healthcheck{Test: Command{"CMD-SHELL", " "}}).Parse(); err == nil {
+		t.Fatal("empty test", "error", nil)
+	}
+}
+
+func TestValidateServices(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+services:
+  db:
+    image: postgres:11
+    variables:
+      POSTGRES_PASSWORD: ""
+    ports:
+    - 5432/http
+    healthcheck:
+      test: ""
+      interval: often
+      retries: -1
+  -bad:
+    ports:
+    - 6379
+  cache:
+    image: redis:5
+    volumes:
+    - data:relative
+    depends_on:
+    - cache
+    - search
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 15: invalid service name \"-bad\"\n" +
+		"line 15: service -bad is missing an image\n" +
+		"line 21: volume target \"relative\" must be an absolute path\n" +
+		"line 23: service cache can't depend on itself\n" +
+		"line 24: service cache depends on unknown service search\n" +
+		"line 8: variable POSTGRES_PASSWORD is empty\n" +
+		"line 10: invalid port protocol \"http\", must be tcp, udp or sctp\n" +
+		"line 11: healthcheck for service db is missing a test\n" +
+		"line 13: invalid interval \"often\", must be a duration like 5s or 1m\n" +
+		"line 14: retries -1 must not be negative"
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
+
+func TestParseVolume(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	tests := map[string]Mount{
+		"/data":             {Type: "volume", Target: "/data"},
+		"pgdata:/data":      {Type: "volume", Source: "pgdata", Target: "/data"},
+		"./init:/init:ro":   {Type: "bind", Source: "./init", Target: "/init", ReadOnly: true},
+		"~/.aws:/root/.aws": {Type: "bind", Source: "~/.aws", Target: "/root/.aws"},
+		"/var/run:/run:rw":  {Type: "bind", Source: "/var/run", Target: "/run"},
+	}
+
+	for spec, expected := range tests {
+		actual, err := ParseVolume(spec)
+		if err != nil {
+			t.Fatal("ParseVolume("+spec+")", nil, err)
+		}
+
+		if expected != actual {
+			t.Fatal("volume for "+spec, expected, actual)
+		}
+	}
+
+	errs := map[string]string{
+		"a:b:c:d":      `invalid volume "a:b:c:d"`,
+		"data:/data:x": `invalid volume mode "x", must be ro or rw`,
+		"data:rel":     `volume target "rel" must be an absolute path`,
+		"da ta:/data":  `invalid volume name "da ta"`,
+	}
+
+	for spec, expected := range errs {
+		_, err := ParseVolume(spec)
+		if err == nil || expected != err.Error() {
+			t.Fatal("ParseVolume("+spec+") error", expected, err)
+		}
+	}
+}
+
+func TestServiceOrder(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	svcs := map[string]Service{
+		"web":   {DependsOn: []string{"queue", "db"}},
+		"queue": {DependsOn: []string{"db"}},
+		"db":    {},
+		"cache": {},
+	}
+
+	order, err := ServiceOrder(svcs)
+	if err != nil {
+		t.Fatal("ServiceOrder()", nil, err)
+	}
+
+	expected := "cache db queue web"
+	if actual := strings.Join(order, " "); expected != actual {
+		t.Fatal("order", expected, actual)
+	}
+
+	svcs["db"] = Service{DependsOn: []string{"web"}}
+
+	_, err = ServiceOrder(svcs)
+	expected = "services depend on each other: db -> web -> db"
+	if err == nil || expected != err.Error() {
+		t.Fatal("ServiceOrder() error", expected, err)
+	}
+
+	svcs["db"] = Service{DependsOn: []string{"search"}}
+
+	_, err = ServiceOrder(svcs)
+	expected = "service db depends on unknown service search"
+	if err == nil || expected != err.Error() {
+		t.Fatal("ServiceOrder() error", expected, err)
+	}
+}
@@ -0,0 +1,520 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// Problem is something wrong with a config file, along with the line it's on.
+// A Line of 0 means the problem is with the file as a whole, like a missing
+// field.
+type Problem struct {
+	Line    int
+	Message string
+}
+
+// Problems are all the problems found in a config file. Load returns them as
+// its error, so that every problem gets reported at once instead of one at a
+// time.
+type Problems []Problem
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+
+	return fmt.Sprintf("line %v: %v", p.Line, p.Message)
+}
+
+func (ps Problems) Error() string {
+	msgs := make([]string, len(ps))
+	for i, p := range ps {
+		msgs[i] = p.String()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+var mountTypes = map[string]bool{
+	"":       true,
+	"bind":   true,
+	"volume": true,
+	"tmpfs":  true,
+}
+
+var volumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// objectName matches the names of services, network aliases and networks.
+var objectName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+var hostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+var protocols = map[string]bool{
+	"tcp":  true,
+	"udp":  true,
+	"sctp": true,
+}
+
+// Validate checks cfg for anything that would otherwise blow up once it's
+// handed to the container engine. raw is the file cfg was read from, which is
+// used to find the line number of every problem.
+func Validate(raw []byte, cfg Opts) Problems {
+	loc := newLocator(raw)
+	ps := Problems{}
+
+	add := func(at string, format string, args ...interface{}) {
+		ps = append(ps, Problem{
+			Line:    loc.line(at),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if cfg.Runtime != "" && !container.Registered(cfg.Runtime) {
+		add("runtime", "unknown runtime %q, see \"envctl runtimes\"",
+			cfg.Runtime)
+	}
+
+	if cfg.Docker.Host != "" && !strings.Contains(cfg.Docker.Host, "://") {
+		add("docker.host", "docker host %q must be a URL, like tcp://%v:2376",
+			cfg.Docker.Host, cfg.Docker.Host)
+	}
+
+	if cfg.Docker.Host != "" && cfg.Docker.Context != "" {
+		add("docker.context", "only one of docker host or context can be set")
+	}
+
+	if cfg.Image == "" && cfg.Build == nil {
+		add("", "missing image or build")
+	}
+
+	if cfg.Image != "" && cfg.Build != nil {
+		add("build", "only one of image or build can be set")
+	}
+
+	if cfg.Shell == "" {
+		add("", "missing shell")
+	}
+
+	if cfg.Mount != "" && !path.IsAbs(cfg.Mount) {
+		add("mount", "mount %q must be an absolute path", cfg.Mount)
+	}
+
+	repoMount := cfg.Mount
+	if repoMount == "" {
+		repoMount = "/mnt/repo"
+	}
+
+	targets := map[string]bool{repoMount: true}
+
+	for i, m := range cfg.Mounts {
+		at := fmt.Sprintf("mounts[%v]", i)
+
+		if !mountTypes[m.Type] {
+			add(at+".type", "invalid mount type %q, must be bind, volume or tmpfs",
+				m.Type)
+		}
+
+		switch {
+		case m.Target == "":
+			add(at, "mount is missing a target")
+		case !path.IsAbs(m.Target):
+			add(at+".target", "mount target %q must be an absolute path", m.Target)
+		case targets[path.Clean(m.Target)]:
+			add(at+".target", "mount target %q is already mounted", m.Target)
+		}
+
+		targets[path.Clean(m.Target)] = true
+
+		switch {
+		case m.Type == "tmpfs" && m.Source != "":
+			add(at+".source", "tmpfs mount can't have a source")
+		case m.Type != "tmpfs" && m.Source == "":
+			add(at, "mount is missing a source")
+		case m.Type == "volume" && !volumeName.MatchString(m.Source):
+			add(at+".source", "invalid volume name %q", m.Source)
+		}
+	}
+
+	for i, c := range cfg.Caches {
+		at := fmt.Sprintf("caches[%v]", i)
+
+		switch {
+		case !path.IsAbs(c):
+			add(at, "cache %q must be an absolute path", c)
+		case targets[path.Clean(c)]:
+			add(at, "cache %q is already mounted", c)
+		}
+
+		targets[path.Clean(c)] = true
+	}
+
+	checkPorts := func(at string, ports Ports) {
+		paths := portPaths(loc, at, ports)
+		for i, spec := range ports {
+			if _, err := ParsePort(spec); err != nil {
+				add(paths[i], "%v", err)
+			}
+		}
+	}
+
+	checkVariables := func(at string, vars map[string]string) {
+		for _, k := range sortedKeys(vars) {
+			v := vars[k]
+			if v == "" {
+				add(at+"."+k, "variable %v is empty", k)
+			} else if v == "$" {
+				add(at+"."+k, "variable %v refers to an unnamed variable", k)
+			}
+		}
+	}
+
+	checkPorts("ports", cfg.Ports)
+
+	res := cfg.Resources
+	if res.CPUs < 0 {
+		add("resources.cpus", "cpus %v must not be negative", res.CPUs)
+	}
+
+	if _, err := parseSize("memory", res.Memory); err != nil {
+		add("resources.memory", "%v", err)
+	}
+
+	if res.PidsLimit < 0 {
+		add("resources.pids_limit", "pids_limit %v must not be negative",
+			res.PidsLimit)
+	}
+
+	if _, err := parseSize("shm_size", res.ShmSize); err != nil {
+		add("resources.shm_size", "%v", err)
+	}
+
+	checkVariables("variables", cfg.Variables)
+
+	for i, step := range cfg.Bootstrap {
+		if strings.TrimSpace(step) == "" {
+			add(fmt.Sprintf("bootstrap[%v]", i), "bootstrap step is empty")
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.Services) {
+		svc := cfg.Services[name]
+		at := "services." + name
+
+		if !objectName.MatchString(name) {
+			add(at, "invalid service name %q", name)
+		}
+
+		if svc.Image == "" {
+			add(at, "service %v is missing an image", name)
+		}
+
+		checkVariables(at+".variables", svc.Variables)
+		checkPorts(at+".ports", svc.Ports)
+
+		for i, spec := range svc.Volumes {
+			if _, err := ParseVolume(spec); err != nil {
+				add(fmt.Sprintf("%v.volumes[%v]", at, i), "%v", err)
+			}
+		}
+
+		// Services can depend on ones in the compose file, which are only
+		// known once it's read.
+		for i, dep := range svc.DependsOn {
+			_, ok := cfg.Services[dep]
+
+			switch {
+			case dep == name:
+				add(fmt.Sprintf("%v.depends_on[%v]", at, i),
+					"service %v can't depend on itself", name)
+			case !ok && cfg.Compose == "":
+				add(fmt.Sprintf("%v.depends_on[%v]", at, i),
+					"service %v depends on unknown service %v", name, dep)
+			}
+		}
+
+		hc := svc.Healthcheck
+		if hc == nil {
+			continue
+		}
+
+		if hc.Test.empty() {
+			add(at+".healthcheck", "healthcheck for service %v is missing a test",
+				name)
+		}
+
+		if _, err := parseDuration("interval", hc.Interval); err != nil {
+			add(at+".healthcheck.interval", "%v", err)
+		}
+
+		if _, err := parseDuration("timeout", hc.Timeout); err != nil {
+			add(at+".healthcheck.timeout", "%v", err)
+		}
+
+		if hc.Retries < 0 {
+			add(at+".healthcheck.retries", "retries %v must not be negative",
+				hc.Retries)
+		}
+	}
+
+	network := cfg.Network
+	if _, ok := cfg.Services[network.Hostname]; ok && network.Hostname != "" {
+		add("network.hostname", "hostname %q is already the name of a service",
+			network.Hostname)
+	} else if network.Hostname != "" && !hostname.MatchString(network.Hostname) {
+		add("network.hostname", "invalid hostname %q", network.Hostname)
+	}
+
+	for i, alias := range network.Aliases {
+		at := fmt.Sprintf("network.aliases[%v]", i)
+
+		if _, ok := cfg.Services[alias]; ok {
+			add(at, "alias %q is already the name of a service", alias)
+		} else if !objectName.MatchString(alias) {
+			add(at, "invalid alias %q", alias)
+		}
+	}
+
+	joined := map[string]bool{}
+	for i, name := range network.External {
+		at := fmt.Sprintf("network.external[%v]", i)
+
+		switch {
+		case !objectName.MatchString(name):
+			add(at, "invalid network name %q", name)
+		case joined[name]:
+			add(at, "network %q is already joined", name)
+		}
+
+		joined[name] = true
+	}
+
+	// The ready probe's ports have to be published for envctl to reach them.
+	if r := cfg.Ready; r != nil {
+		published := func(port int) bool {
+			bindings, _ := cfg.Ports.Bindings()
+			for _, b := range bindings {
+				if b.ContainerPort == port && b.Protocol == "tcp" {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		switch {
+		case r.Command == "" && r.Port == 0 && r.URL == "":
+			add("ready", "ready is missing a command, port or url")
+		case (r.Command != "" && (r.Port != 0 || r.URL != "")) ||
+			(r.Port != 0 && r.URL != ""):
+
+			add("ready", "only one of command, port or url can be set for ready")
+		}
+
+		switch {
+		case r.Port < 0 || r.Port > 65535:
+			add("ready.port", "invalid port %v", r.Port)
+		case r.Port != 0 && !published(r.Port):
+			add("ready.port", "port %v isn't published, add it to ports", r.Port)
+		}
+
+		if r.URL != "" {
+			port, err := URLPort(r.URL)
+			switch {
+			case err != nil:
+				add("ready.url", "%v", err)
+			case !published(port):
+				add("ready.url", "port %v isn't published, add it to ports", port)
+			}
+		}
+
+		if _, err := parseDuration("timeout", r.Timeout); err != nil {
+			add("ready.timeout", "%v", err)
+		}
+
+		if _, err := parseDuration("interval", r.Interval); err != nil {
+			add("ready.interval", "%v", err)
+		}
+	}
+
+	return ps
+}
+
+// portPaths returns the path to every port at the given path in the config
+// file, for the locator. Ports in the older form are found by protocol, in
+// the order UnmarshalYAML put them in.
+func portPaths(loc locator, at string, ports Ports) []string {
+	paths := make([]string, len(ports))
+
+	_, list := loc[at+"[0]"]
+	counts := map[string]int{}
+
+	for i, spec := range ports {
+		if list {
+			paths[i] = fmt.Sprintf("%v[%v]", at, i)
+			continue
+		}
+
+		proto := spec[strings.LastIndex(spec, "/")+1:]
+		paths[i] = fmt.Sprintf("%v.%v[%v]", at, proto, counts[proto])
+		counts[proto]++
+	}
+
+	return paths
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+
+	switch m := m.(type) {
+	case L3Ports:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]Service:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]composeService:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// locator maps paths to fields in a YAML document, like "ports.tcp[1]", to
+// the lines they're on. The YAML library doesn't keep track of positions
+// once it's unmarshaled a document, so the locator works them out from the
+// document's indentation instead. It only understands block style YAML, which
+// is what config files are written in.
+type locator map[string]int
+
+var yamlKey = regexp.MustCompile(`^([^\s#'"{\[][^:#]*?|"[^"]*"|'[^']*'):(\s+|$)(.*)$`)
+
+type locatorEntry struct {
+	indent int
+	path   string
+	// open is set for keys with nothing after the colon, which can have a
+	// sequence at the same indentation as them.
+	open  bool
+	items int
+}
+
+func newLocator(raw []byte) locator {
+	loc := locator{}
+	stack := []*locatorEntry{}
+	blockIndent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		content := strings.TrimLeft(line, " ")
+		indent := len(line) - len(content)
+
+		if content == "" || strings.HasPrefix(content, "#") ||
+			strings.HasPrefix(content, "---") {
+
+			continue
+		}
+
+		// Skip over the contents of block scalars, which can look like
+		// anything.
+		if blockIndent >= 0 {
+			if indent > blockIndent {
+				continue
+			}
+
+			blockIndent = -1
+		}
+
+		for strings.HasPrefix(content, "- ") || content == "-" {
+			for len(stack) > 0 {
+				top := stack[len(stack)-1]
+				if top.indent < indent || (top.indent == indent && top.open) {
+					break
+				}
+
+				stack = stack[:len(stack)-1]
+			}
+
+			parent := &locatorEntry{indent: -1}
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			p := fmt.Sprintf("%v[%v]", parent.path, parent.items)
+			parent.items++
+			loc[p] = n
+
+			item := strings.TrimPrefix(content, "-")
+			trimmed := strings.TrimLeft(item, " ")
+			indent += 1 + len(item) - len(trimmed)
+			content = trimmed
+
+			stack = append(stack, &locatorEntry{indent: indent - 1, path: p})
+		}
+
+		m := yamlKey.FindStringSubmatch(content)
+		if m == nil {
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		key := strings.Trim(m[1], `"'`)
+		p := key
+		if len(stack) > 0 {
+			p = stack[len(stack)-1].path + "." + key
+		}
+
+		loc[p] = n
+
+		value := strings.TrimSpace(m[3])
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockIndent = indent
+		}
+
+		stack = append(stack, &locatorEntry{
+			indent: indent,
+			path:   p,
+			open:   value == "" || strings.HasPrefix(value, "#"),
+		})
+	}
+
+	return loc
+}
+
+// line returns the line the field at the given path is on. If it isn't in the
+// document, it falls back to the closest field that contains it, and if there
+// isn't one, to 0.
+func (loc locator) line(at string) int {
+	for at != "" {
+		if n, ok := loc[at]; ok {
+			return n
+		}
+
+		i := strings.LastIndexAny(at, ".[")
+		if i < 0 {
+			break
+		}
+
+		at = at[:i]
+	}
+
+	return 0
+}
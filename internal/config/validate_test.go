@@ -0,0 +1,282 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestValidate(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+
+shell: /bin/bash
+
+mount: repo
+
+bootstrap:
+- make deps
+- " "
+
+variables:
+  FOO: bar
+  EMPTY: ""
+  DOLLAR: $
+
+ports:
+  tcp:
+  - 4567
+  - 70000
+  http:
+  - 80
+
+runtime: lxc
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := Problems{
+		{Line: 24, Message: `unknown runtime "lxc", see "envctl runtimes"`},
+		{Line: 6, Message: `mount "repo" must be an absolute path`},
+		{Line: 22, Message: `invalid port protocol "http", must be tcp, udp or sctp`},
+		{Line: 20, Message: "port 70000 is out of range, must be between 1 and 65535"},
+		{Line: 15, Message: "variable DOLLAR refers to an unnamed variable"},
+		{Line: 14, Message: "variable EMPTY is empty"},
+		{Line: 10, Message: "bootstrap step is empty"},
+	}
+
+	actual := Validate(raw, cfg)
+	if len(expected) != len(actual) {
+		t.Fatal("problems", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatal("problems", expected, actual)
+		}
+	}
+}
+
+func TestValidateMissingFields(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+build:
+  context: .
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 3: only one of image or build can be set\nmissing shell"
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
+
+func TestValidateDocker(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+docker:
+  host: build-host
+  context: build
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 5: docker host \"build-host\" must be a URL, like tcp://build-host:2376\n" +
+		"line 6: only one of docker host or context can be set"
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
+
+func TestValidateMounts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+mount: /src
+mounts:
+- source: ~/.ssh
+  target: /root/.ssh
+  read_only: true
+- type: volume
+  source: m2
+  target: /src
+- type: nfs
+  source: server:/export
+  target: cache
+- type: tmpfs
+  source: ram
+  target: /tmp
+- type: volume
+  source: ../m2
+  target: /root/.m2
+- target: /data
+caches:
+- /root/.npm
+- node_modules
+- /root/.ssh
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := Problems{
+		{Line: 11, Message: `mount target "/src" is already mounted`},
+		{Line: 12, Message: `invalid mount type "nfs", must be bind, volume or tmpfs`},
+		{Line: 14, Message: `mount target "cache" must be an absolute path`},
+		{Line: 16, Message: "tmpfs mount can't have a source"},
+		{Line: 19, Message: `invalid volume name "../m2"`},
+		{Line: 21, Message: "mount is missing a source"},
+		{Line: 24, Message: `cache "node_modules" must be an absolute path`},
+		{Line: 25, Message: `cache "/root/.ssh" is already mounted`},
+	}
+
+	actual := Validate(raw, cfg)
+	if len(expected) != len(actual) {
+		t.Fatal("problems", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i] != actual[i] {
+			t.Fatal("problems", expected, actual)
+		}
+	}
+}
+
+func TestLocator(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+# a comment
+image: ubuntu:latest
+bootstrap:
+- |
+  echo foo:
+  echo bar
+- make
+services:
+  db:
+    ports:
+      - 5432
+      - 5433
+    mounts:
+    - type: volume
+      target: /data
+    - type: tmpfs
+      target: /tmp
+user: root
+`)
+
+	expected := map[string]int{
+		"image":                        3,
+		"bootstrap":                    4,
+		"bootstrap[0]":                 5,
+		"bootstrap[1]":                 8,
+		"services.db":                  10,
+		"services.db.ports[1]":         13,
+		"services.db.mounts[0].type":   15,
+		"services.db.mounts[1]":        17,
+		"services.db.mounts[1].target": 18,
+		"user":                         19,
+		"services.db.missing":          10,
+		"missing":                      0,
+	}
+
+	loc := newLocator(raw)
+	for at, line := range expected {
+		if actual := loc.line(at); line != actual {
+			t.Fatal("line of "+at, line, actual)
+		}
+	}
+}
+
+func TestValidateResources(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+resources:
+  cpus: -1
+  memory: lots
+  pids_limit: -5
+  shm_size: 64m
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 5: cpus -1 must not be negative\n" +
+		"line 6: invalid memory \"lots\", must be a size like 512m or 2g\n" +
+		"line 7: pids_limit -5 must not be negative"
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
+
+func TestValidateNetwork(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := []byte(`---
+image: ubuntu:latest
+shell: /bin/bash
+services:
+  db:
+    image: postgres:11
+network:
+  hostname: app_1
+  aliases:
+  - api
+  - db
+  - -web
+  external:
+  - compose_default
+  - compose_default
+  - bad name
+`)
+
+	var cfg Opts
+	if err := yaml.UnmarshalStrict(raw, &cfg); err != nil {
+		t.Fatal("unmarshaling test config", nil, err)
+	}
+
+	expected := "line 8: invalid hostname \"app_1\"\n" +
+		"line 11: alias \"db\" is already the name of a service\n" +
+		"line 12: invalid alias \"-web\"\n" +
+		"line 15: network \"compose_default\" is already joined\n" +
+		"line 16: invalid network name \"bad name\""
+
+	actual := Validate(raw, cfg)
+	if expected != actual.Error() {
+		t.Fatal("problems", expected, actual.Error())
+	}
+}
@@ -1,8 +1,9 @@
 package config
 
 import (
-	"errors"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -21,9 +22,10 @@ type YAML struct {
 	Path string
 }
 
-// Load returns a new `Opts`` by reading the YAML file. If an error
+// Load returns a new `Opts` by reading the YAML file. If an error
 // happens along the way it returns it along with a zeroed `Opts`. If
-// something is missing that should be there, it'll return an error.
+// something is missing that should be there, or anything else about it is
+// invalid, it'll return the Problems with it as the error.
 func (c YAML) Load() (Opts, error) {
 	f, err := ioutil.ReadFile(c.Path)
 	if err != nil {
@@ -36,12 +38,14 @@ func (c YAML) Load() (Opts, error) {
 		return Opts{}, err
 	}
 
-	if cfg.Image == "" {
-		return Opts{}, errors.New("missing image")
+	if ps := Validate(f, cfg); len(ps) > 0 {
+		return Opts{}, ps
 	}
 
-	if cfg.Shell == "" {
-		return Opts{}, errors.New("missing shell")
+	if cfg.Compose != "" {
+		if err := c.loadCompose(&cfg); err != nil {
+			return Opts{}, err
+		}
 	}
 
 	if cfg.CacheImage == nil {
@@ -54,3 +58,42 @@ func (c YAML) Load() (Opts, error) {
 
 	return cfg, nil
 }
+
+// loadCompose adds the services in cfg's compose file to the ones in the
+// config file. Services can depend on each other across the two files, but
+// can't be in both.
+func (c YAML) loadCompose(cfg *Opts) error {
+	file := cfg.Compose
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(c.Path), file)
+	}
+
+	svcs, err := LoadCompose(file)
+	if err != nil {
+		return fmt.Errorf("error reading %v: %v", cfg.Compose, err)
+	}
+
+	if cfg.Services == nil {
+		cfg.Services = map[string]Service{}
+	}
+
+	ps := Problems{}
+	for _, name := range sortedKeys(svcs) {
+		if _, ok := cfg.Services[name]; ok {
+			ps = append(ps, Problem{Message: fmt.Sprintf(
+				"service %v is in both the config file and %v", name, cfg.Compose)})
+		}
+
+		cfg.Services[name] = svcs[name]
+	}
+
+	if len(ps) > 0 {
+		return ps
+	}
+
+	if _, err := ServiceOrder(cfg.Services); err != nil {
+		return Problems{{Message: err.Error()}}
+	}
+
+	return nil
+}
@@ -1,38 +1,273 @@
 package container
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
 
 // Metadata is what's returned by the container functions. It contains
 // everything that a consumer of this package needs to know about containers
 // being managed.
 type Metadata struct {
-	ID        string           `json:"id"`
-	ImageID   string           `json:"image_id"`
-	BaseName  string           `json:"base_name"`
-	BaseImage string           `json:"base_image"`
-	Shell     string           `json:"shell"`
-	Mount     Mount            `json:"mount"`
-	Envs      []string         `json:"envs"`
-	NoCache   bool             `json:"no_cache"`
-	User      string           `json:"user"`
-	Ports     map[string][]int `json:"ports"`
-}
-
-// Mount is directory on the host paired with a volume mount point.
+	ID        string   `json:"id"`
+	ImageID   string   `json:"image_id"`
+	BaseName  string   `json:"base_name"`
+	BaseImage string   `json:"base_image"`
+	Shell     string   `json:"shell"`
+	Mount     Mount    `json:"mount"`
+	Envs      []string `json:"envs"`
+	NoCache   bool     `json:"no_cache"`
+	User      string   `json:"user"`
+	Build     *Build   `json:"build,omitempty"`
+	// Ports are the container's ports to publish on the host.
+	Ports []PortBinding `json:"port_bindings,omitempty"`
+	// Bootstrap are commands baked into the image at build time, each run with
+	// Shell. Docker caches the resulting layers, so unchanged steps don't run
+	// again the next time the image is built.
+	Bootstrap []string `json:"bootstrap,omitempty"`
+	// Mounts are mounted in the container on top of Mount.
+	Mounts []Mount `json:"mounts,omitempty"`
+	// Runtime is the container runtime the container was created with. An
+	// empty Runtime means "docker", since that's all there was before.
+	Runtime string `json:"runtime,omitempty"`
+	// RuntimeOptions are the options the runtime's controller was created
+	// with, like the Docker host, so that the container is looked for in the
+	// same place later on. They're nil for containers created before they
+	// were kept track of.
+	RuntimeOptions Options `json:"runtime_options"`
+	// Resources limit what the container can use of the host.
+	Resources Resources `json:"resources"`
+	// Services run alongside the container. They're created, started,
+	// stopped and removed along with it.
+	Services []Service `json:"services,omitempty"`
+	// Network is the network the container and its services are on. It
+	// belongs to the container, and is removed along with it.
+	Network string `json:"network,omitempty"`
+	// Hostname is the container's hostname. It's also one of the names the
+	// container can be reached at on Network. The container engine picks one
+	// when it's empty.
+	Hostname string `json:"hostname,omitempty"`
+	// Aliases are other names the container can be reached at on Network.
+	Aliases []string `json:"aliases,omitempty"`
+	// ExternalNetworks are networks that already exist, like one created by
+	// docker-compose, for the container to join besides Network. They're left
+	// alone when the container is removed.
+	ExternalNetworks []string `json:"external_networks,omitempty"`
+	// Ready is how to tell that what runs in the container, like a dev
+	// server, is ready for use. Without it, the container is ready as soon as
+	// it's created.
+	Ready *Probe `json:"ready,omitempty"`
+}
+
+// Probe checks that something in a container is ready for use. Only one of
+// Command, Port and URL is set.
+type Probe struct {
+	// Command is run with the container's shell, and passes when it exits
+	// with status 0.
+	Command string `json:"command,omitempty"`
+	// Port is a TCP port of the container, which passes once it accepts a
+	// connection. It's reached through the host port it's published on.
+	Port int `json:"port,omitempty"`
+	// URL is an HTTP URL as seen from inside the container, like
+	// "http://localhost:3000/health", which passes once it responds with a
+	// status below 400. It's reached through the host port its port is
+	// published on.
+	URL string `json:"url,omitempty"`
+	// Timeout is how long the probe gets to pass once the container is
+	// created.
+	Timeout time.Duration `json:"timeout"`
+	// Interval is the time between attempts.
+	Interval time.Duration `json:"interval"`
+}
+
+// Resources are limits on what a container can use. A limit of 0 means there
+// isn't one.
+type Resources struct {
+	// NanoCPUs is how many CPUs the container can use, in billionths of a
+	// CPU.
+	NanoCPUs int64 `json:"nano_cpus,omitempty"`
+	// Memory is how much memory the container can use, in bytes.
+	Memory int64 `json:"memory,omitempty"`
+	// PidsLimit is how many processes can run in the container at once.
+	PidsLimit int64 `json:"pids_limit,omitempty"`
+	// ShmSize is the size of /dev/shm, in bytes. The container engine picks
+	// it when it's 0.
+	ShmSize int64 `json:"shm_size,omitempty"`
+}
+
+// Build is how to build the base image from a Dockerfile, instead of using an
+// existing one.
+type Build struct {
+	// Context is the absolute path to the directory sent to the builder.
+	Context string `json:"context"`
+	// Dockerfile is the path to the Dockerfile, relative to Context.
+	Dockerfile string            `json:"dockerfile"`
+	Args       map[string]string `json:"args,omitempty"`
+	// Target is the stage to build in a multi-stage Dockerfile.
+	Target string `json:"target,omitempty"`
+}
+
+// Mount is something mounted in the container, usually a directory on the
+// host, paired with a mount point.
 type Mount struct {
+	// Type is what's being mounted. It defaults to MountBind.
+	Type MountType `json:"type,omitempty"`
+	// Source is the absolute path on the host for bind mounts, and the name
+	// of the volume for volumes. It's empty for tmpfs mounts.
 	Source      string `json:"source"`
 	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"read_only,omitempty"`
+}
+
+// PortBinding publishes a port of the container on the host.
+type PortBinding struct {
+	// HostIP is the address on the host to listen on. It's empty for every
+	// address.
+	HostIP string `json:"host_ip,omitempty"`
+	// HostPort is the port on the host. When it's 0, the container engine
+	// picks a free one.
+	HostPort      int    `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
 }
 
+// MountType is what kind of thing a Mount mounts.
+type MountType string
+
+const (
+	// MountBind mounts a directory or file from the host.
+	MountBind MountType = "bind"
+	// MountVolume mounts a named volume managed by the container engine.
+	// Volumes are left alone when the container is removed, so whatever's in
+	// them is still there for the next container that mounts them.
+	MountVolume MountType = "volume"
+	// MountTmpfs mounts an in-memory filesystem, which is gone once the
+	// container stops.
+	MountTmpfs MountType = "tmpfs"
+)
+
 // Controller can control containers. This includes allowing consumers to
 // attach to the container.
 type Controller interface {
 	Create(Metadata) (Metadata, error)
 	Remove(Metadata) error
+	Start(Metadata) error
+	Stop(Metadata) error
 	Attach(Metadata) error
-	Run(Metadata, []string) error
+	Run(Metadata, []string, RunOpts) error
+	Inspect(Metadata) (State, error)
+}
+
+// Status is what the container engine says a container is doing.
+type Status string
+
+const (
+	// StatusCreated is a container's status when it exists, but has never been
+	// started.
+	StatusCreated Status = "created"
+	// StatusRunning is a container's status when its main process is running.
+	StatusRunning Status = "running"
+	// StatusExited is a container's status when its main process has stopped.
+	StatusExited Status = "exited"
+	// StatusMissing is a container's status when the container engine doesn't
+	// know about it anymore.
+	StatusMissing Status = "missing"
+	// StatusImageMissing is a container's status when it exists, but the
+	// image it was built from doesn't.
+	StatusImageMissing Status = "image missing"
+)
+
+// State is the live state of a container, as reported by the container engine.
+type State struct {
+	Status   Status
+	ExitCode int
+	// Ports are the ports the container engine published for the container
+	// while it's running, with the host ports it picked filled in.
+	Ports []PortBinding
+}
+
+// RunOpts controls how a command run by a Controller is hooked up to the
+// current process.
+type RunOpts struct {
+	// TTY allocates a pseudo-terminal for the command. Its stdout and stderr
+	// get merged into the terminal's output. Without it, they're kept separate.
+	TTY bool
+	// Stdin forwards the current process's stdin to the command. It's only
+	// used without a TTY.
+	Stdin bool
+}
+
+// ExitError is returned by a Controller's Run when the command it ran exited
+// with a non-zero status.
+type ExitError struct {
+	Cmd  []string
+	Code int
+}
+
+// UnmarshalJSON reads Metadata, converting the ports of metadata saved before
+// ports could be published on other host ports. Those were kept under "ports"
+// as a map of protocols to ports, each published on the same port on the
+// host.
+func (m *Metadata) UnmarshalJSON(buf []byte) error {
+	type metadata Metadata
+
+	var raw struct {
+		metadata
+		LegacyPorts map[string][]int `json:"ports"`
+	}
+
+	if err := json.Unmarshal(buf, &raw); err != nil {
+		return err
+	}
+
+	*m = Metadata(raw.metadata)
+
+	if len(m.Ports) > 0 {
+		return nil
+	}
+
+	protos := []string{}
+	for proto := range raw.LegacyPorts {
+		protos = append(protos, proto)
+	}
+
+	sort.Strings(protos)
+
+	for _, proto := range protos {
+		for _, port := range raw.LegacyPorts[proto] {
+			m.Ports = append(m.Ports, PortBinding{
+				HostPort:      port,
+				ContainerPort: port,
+				Protocol:      proto,
+			})
+		}
+	}
+
+	return nil
 }
 
 func (m Mount) String() string {
 	return fmt.Sprintf("%v:%v", m.Source, m.Destination)
 }
+
+// String returns the binding the way the docker CLI shows it, like
+// "127.0.0.1:8080->80/tcp".
+func (p PortBinding) String() string {
+	host := "auto"
+	if p.HostPort != 0 {
+		host = fmt.Sprint(p.HostPort)
+	}
+
+	if p.HostIP != "" {
+		host = net.JoinHostPort(p.HostIP, host)
+	}
+
+	return fmt.Sprintf("%v->%v/%v", host, p.ContainerPort, p.Protocol)
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%v exited with status %v", e.Cmd, e.Code)
+}
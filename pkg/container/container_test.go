@@ -0,0 +1,50 @@
+package container
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestUnmarshalLegacyPorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := `{"id": "foocnt", "ports": {"udp": [53], "tcp": [80, 443]}}`
+
+	var m Metadata
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		t.Fatal("Unmarshal()", nil, err)
+	}
+
+	expected := []PortBinding{
+		{HostPort: 80, ContainerPort: 80, Protocol: "tcp"},
+		{HostPort: 443, ContainerPort: 443, Protocol: "tcp"},
+		{HostPort: 53, ContainerPort: 53, Protocol: "udp"},
+	}
+
+	if "foocnt" != m.ID || len(expected) != len(m.Ports) {
+		t.Fatal("metadata", expected, m)
+	}
+
+	for i := range expected {
+		if expected[i] != m.Ports[i] {
+			t.Fatal("ports", expected, m.Ports)
+		}
+	}
+
+	// Metadata saved since then round-trips as it is.
+	buf, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal("Marshal()", nil, err)
+	}
+
+	var again Metadata
+	if err := json.Unmarshal(buf, &again); err != nil {
+		t.Fatal("Unmarshal()", nil, err)
+	}
+
+	if len(expected) != len(again.Ports) || expected[2] != again.Ports[2] {
+		t.Fatal("ports after a round trip", expected, again.Ports)
+	}
+}
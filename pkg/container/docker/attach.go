@@ -29,13 +29,17 @@ func (c *Controller) Attach(m container.Metadata) error {
 	}
 
 	resp, err := c.client.ContainerAttach(context.Background(), m.ID, acfg)
-	defer resp.Close()
 	if err != nil {
+		restoreStdin()
+		restoreStdout()
 		return err
 	}
+	defer resp.Close()
 
-	errchan := make(chan error)
-	donechan := make(chan struct{})
+	// Both copies can fail, and only the first error is waited for, so the
+	// channel has room for the other one not to block.
+	errchan := make(chan error, 2)
+	donechan := make(chan struct{}, 1)
 
 	err = c.client.ContainerStart(
 		context.Background(),
@@ -43,6 +47,8 @@ func (c *Controller) Attach(m container.Metadata) error {
 		types.ContainerStartOptions{},
 	)
 	if err != nil {
+		restoreStdin()
+		restoreStdout()
 		return err
 	}
 
@@ -99,28 +105,37 @@ func (c *Controller) Attach(m container.Metadata) error {
 // succeed in doing so depending on what the issue was. If it was successful,
 // it returns two callback functions for the caller to restore the terminal
 // back to its previous state when ready. The first is for stdout, the second
-// is for stderr.
+// is for stdin. Streams that aren't terminals, like when input is piped in,
+// are left alone, and their callbacks do nothing.
 func (c *Controller) makeRawTerminal() (func() error, func() error, error) {
+	noop := func() error { return nil }
+
 	// This stuff is required to make interactive sessions in the container
 	// less buggy. For example, without it, any command typed at the prompt will
 	// get repeated out before printing the execution results.
-	oldStdout, err := term.MakeRaw(c.stdout.fd)
-	if err != nil {
-		return nil, nil, err
-	}
+	restoreStdout := noop
+	if term.IsTerminal(c.stdout.fd) {
+		oldStdout, err := term.MakeRaw(c.stdout.fd)
+		if err != nil {
+			return nil, nil, err
+		}
 
-	restoreStdout := func() error {
-		return term.RestoreTerminal(c.stdout.fd, oldStdout)
+		restoreStdout = func() error {
+			return term.RestoreTerminal(c.stdout.fd, oldStdout)
+		}
 	}
 
-	oldStdin, err := term.MakeRaw(c.stdin.fd)
-	if err != nil {
-		term.RestoreTerminal(c.stdout.fd, oldStdout)
-		return nil, nil, err
-	}
+	restoreStdin := noop
+	if term.IsTerminal(c.stdin.fd) {
+		oldStdin, err := term.MakeRaw(c.stdin.fd)
+		if err != nil {
+			restoreStdout()
+			return nil, nil, err
+		}
 
-	restoreStdin := func() error {
-		return term.RestoreTerminal(c.stdin.fd, oldStdin)
+		restoreStdin = func() error {
+			return term.RestoreTerminal(c.stdin.fd, oldStdin)
+		}
 	}
 
 	return restoreStdout, restoreStdin, nil
@@ -0,0 +1,70 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestAttach(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	d.attachOutput = "root@envctl:/# exit\r\n"
+
+	c := d.controller(&t)
+	output := d.capture(&t, c)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.Attach(m); err != nil {
+		t.Fatal("Attach()", nil, err)
+	}
+
+	if actual := output(); !strings.Contains(actual, d.attachOutput) {
+		t.Fatal("output", d.attachOutput, actual)
+	}
+
+	if "running" != d.containers[m.ID].Status {
+		t.Fatal("container status", "running", d.containers[m.ID].Status)
+	}
+}
+
+func TestAttachStartError(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	d.errors["POST /containers/"+m.ID+"/start"] = "500 port is already allocated"
+
+	err = c.Attach(m)
+	if err == nil || !strings.Contains(err.Error(), "port is already allocated") {
+		t.Fatal("Attach() error", "port is already allocated", err)
+	}
+}
+
+func TestAttachMissingContainer(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	if err := d.controller(&t).Attach(container.Metadata{ID: "nope"}); err == nil {
+		t.Fatal("Attach() error", "no such container", err)
+	}
+}
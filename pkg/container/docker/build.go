@@ -0,0 +1,75 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/dockerfile"
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+)
+
+// buildBaseImage builds the image described by the metadata's Build settings,
+// which the environment's own image is then built on top of. It returns the
+// name of the built image, as <m.BaseName-base:UUID>, or an error.
+func (c *Controller) buildBaseImage(m container.Metadata) (string, error) {
+	b := m.Build
+
+	df := b.Dockerfile
+	if df == "" {
+		df = "Dockerfile"
+	}
+
+	raw, err := ioutil.ReadFile(filepath.Join(b.Context, df))
+	if err != nil {
+		return "", err
+	}
+
+	// The version of the Docker API being used doesn't know about build
+	// targets, so the Dockerfile is cut off after the target stage instead.
+	// Since stages can only depend on the ones before them, this builds the
+	// same image.
+	if b.Target != "" {
+		raw, err = dockerfile.Truncate(raw, b.Target)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	buildContext, dfName, err := dockerfile.DirContext(b.Context, df, raw)
+	if err != nil {
+		return "", err
+	}
+
+	args := map[string]*string{}
+	for k, v := range b.Args {
+		v := v
+		args[k] = &v
+	}
+
+	name := fmt.Sprintf("%v-base:%v", m.BaseName, uuid.New().String())
+	bldopts := types.ImageBuildOptions{
+		Tags:       []string{name},
+		NoCache:    m.NoCache,
+		Dockerfile: dfName,
+		BuildArgs:  args,
+	}
+
+	resp, err := c.client.ImageBuild(context.Background(), buildContext, bldopts)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	// the read MUST happen, if not the program will continue without waiting
+	// for the build to complete
+	if err := c.showProgress(resp.Body); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
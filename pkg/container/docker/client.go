@@ -0,0 +1,205 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/sockets"
+	"github.com/docker/go-connections/tlsconfig"
+)
+
+// ClientOpts are the settings for connecting to the Docker daemon. They
+// correspond to the docker CLI's global flags. Anything left empty falls back
+// to the matching DOCKER_* environment variable, and then to the defaults.
+type ClientOpts struct {
+	// Host is the daemon socket to connect to, like
+	// "tcp://build-host:2376".
+	Host string
+	// CertPath is a directory holding ca.pem, cert.pem and key.pem, for
+	// connecting to the daemon over TLS.
+	CertPath string
+	// APIVersion is the version of the Engine API to use.
+	APIVersion string
+	// Context is a context created with "docker context create", which
+	// provides the host and TLS settings unless Host is set.
+	Context string
+}
+
+// dockerContext is the part of a Docker context's metadata that says how to
+// connect to the daemon.
+type dockerContext struct {
+	Endpoints struct {
+		Docker struct {
+			Host          string
+			SkipTLSVerify bool
+		} `json:"docker"`
+	}
+}
+
+// NewControllerWithOpts returns a `*Controller` like NewController, but
+// connecting to the Docker daemon with the given settings.
+func NewControllerWithOpts(opts ClientOpts) (*Controller, error) {
+	cli, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewControllerWithClient(cli), nil
+}
+
+func newClient(opts ClientOpts) (*client.Client, error) {
+	// Certificates given explicitly are always verified. The ones from the
+	// environment are only verified if DOCKER_TLS_VERIFY is set, like with
+	// the docker CLI.
+	verify := opts.CertPath != "" || os.Getenv("DOCKER_TLS_VERIFY") != ""
+	opts = opts.withEnv()
+
+	if opts == (ClientOpts{}) {
+		return client.NewEnvClient()
+	}
+
+	var tlsc *tlsFiles
+
+	if opts.Host == "" && opts.Context != "" && opts.Context != "default" {
+		ctx, err := readContext(opts.Context)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.Host = ctx.host
+		tlsc = ctx.tls
+	}
+
+	if opts.Host == "" {
+		opts.Host = client.DefaultDockerHost
+	}
+
+	if opts.APIVersion == "" {
+		opts.APIVersion = client.DefaultVersion
+	}
+
+	if opts.CertPath != "" {
+		tlsc = &tlsFiles{
+			dir:    opts.CertPath,
+			verify: verify,
+		}
+	}
+
+	proto, addr, _, err := client.ParseHost(opts.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &http.Transport{}
+	if err := sockets.ConfigureTransport(transport, proto, addr); err != nil {
+		return nil, err
+	}
+
+	if tlsc != nil {
+		transport.TLSClientConfig, err = tlsconfig.Client(tlsconfig.Options{
+			CAFile:             filepath.Join(tlsc.dir, "ca.pem"),
+			CertFile:           filepath.Join(tlsc.dir, "cert.pem"),
+			KeyFile:            filepath.Join(tlsc.dir, "key.pem"),
+			InsecureSkipVerify: !tlsc.verify,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS certificates: %v", err)
+		}
+	}
+
+	return client.NewClient(
+		opts.Host,
+		opts.APIVersion,
+		&http.Client{Transport: transport},
+		nil,
+	)
+}
+
+// tlsFiles is where to find the certificates for a TLS connection, and
+// whether the daemon's certificate should be verified.
+type tlsFiles struct {
+	dir    string
+	verify bool
+}
+
+type resolvedContext struct {
+	host string
+	tls  *tlsFiles
+}
+
+// withEnv fills in whatever isn't set from the environment, the same way the
+// docker CLI does. A host from the environment takes precedence over a
+// context from it, but never over one that was given explicitly.
+func (opts ClientOpts) withEnv() ClientOpts {
+	if opts.Host == "" && opts.Context == "" {
+		opts.Host = os.Getenv("DOCKER_HOST")
+		if opts.Host == "" {
+			opts.Context = os.Getenv("DOCKER_CONTEXT")
+		}
+	}
+
+	if opts.CertPath == "" {
+		opts.CertPath = os.Getenv("DOCKER_CERT_PATH")
+	}
+
+	if opts.APIVersion == "" {
+		opts.APIVersion = os.Getenv("DOCKER_API_VERSION")
+	}
+
+	return opts
+}
+
+// readContext reads the connection settings of the named context from the
+// docker CLI's config directory. Contexts are stored under the SHA-256 of
+// their name.
+func readContext(name string) (resolvedContext, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return resolvedContext{}, err
+		}
+
+		dir = filepath.Join(home, ".docker")
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+
+	raw, err := ioutil.ReadFile(filepath.Join(dir, "contexts", "meta", id, "meta.json"))
+	if os.IsNotExist(err) {
+		return resolvedContext{}, fmt.Errorf("docker context %q not found", name)
+	}
+
+	if err != nil {
+		return resolvedContext{}, err
+	}
+
+	var ctx dockerContext
+	if err := json.Unmarshal(raw, &ctx); err != nil {
+		return resolvedContext{}, fmt.Errorf("reading docker context %q: %v", name, err)
+	}
+
+	if ctx.Endpoints.Docker.Host == "" {
+		return resolvedContext{}, fmt.Errorf("docker context %q has no docker endpoint", name)
+	}
+
+	resolved := resolvedContext{host: ctx.Endpoints.Docker.Host}
+
+	tlsdir := filepath.Join(dir, "contexts", "tls", id, "docker")
+	if _, err := os.Stat(tlsdir); err == nil {
+		resolved.tls = &tlsFiles{
+			dir:    tlsdir,
+			verify: !ctx.Endpoints.Docker.SkipTLSVerify,
+		}
+	}
+
+	return resolved, nil
+}
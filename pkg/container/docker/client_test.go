@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+// writeContext creates a Docker context the way "docker context create" does,
+// in a temp dir used as DOCKER_CONFIG. It returns a function that removes it
+// and restores DOCKER_CONFIG.
+func writeContext(t *test_pkg.T, name, meta string, tls bool) func() {
+	dir, err := ioutil.TempDir("", "envctl-docker-config")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(sum[:])
+
+	metadir := filepath.Join(dir, "contexts", "meta", id)
+	if err := os.MkdirAll(metadir, 0755); err != nil {
+		t.Fatal("creating context dir", nil, err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(metadir, "meta.json"), []byte(meta), 0644)
+	if err != nil {
+		t.Fatal("writing context", nil, err)
+	}
+
+	if tls {
+		err := os.MkdirAll(filepath.Join(dir, "contexts", "tls", id, "docker"), 0755)
+		if err != nil {
+			t.Fatal("creating tls dir", nil, err)
+		}
+	}
+
+	old, set := os.LookupEnv("DOCKER_CONFIG")
+	os.Setenv("DOCKER_CONFIG", dir)
+
+	return func() {
+		if set {
+			os.Setenv("DOCKER_CONFIG", old)
+		} else {
+			os.Unsetenv("DOCKER_CONFIG")
+		}
+
+		os.RemoveAll(dir)
+	}
+}
+
+func TestReadContext(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	defer writeContext(&t, "build", `{
+  "Name": "build",
+  "Metadata": {},
+  "Endpoints": {
+    "docker": {"Host": "tcp://build-host:2376", "SkipTLSVerify": true}
+  }
+}`, true)()
+
+	ctx, err := readContext("build")
+	if err != nil {
+		t.Fatal("readContext()", nil, err)
+	}
+
+	if "tcp://build-host:2376" != ctx.host {
+		t.Fatal("context host", "tcp://build-host:2376", ctx.host)
+	}
+
+	if ctx.tls == nil || ctx.tls.verify {
+		t.Fatal("context tls", "unverified", ctx.tls)
+	}
+
+	if _, err := readContext("missing"); err == nil {
+		t.Fatal("readContext() error", "not found", err)
+	}
+}
+
+func TestNewControllerWithOpts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	host := "unix://" + filepath.Join(d.dir, "docker.sock")
+
+	c, err := NewControllerWithOpts(ClientOpts{Host: host})
+	if err != nil {
+		t.Fatal("NewControllerWithOpts()", nil, err)
+	}
+
+	if err := c.Ping(); err != nil {
+		t.Fatal("Ping()", nil, err)
+	}
+
+	// The same daemon, found through a context instead.
+	defer writeContext(&t, "local", `{"Endpoints":{"docker":{"Host":"`+host+`"}}}`, false)()
+
+	c, err = NewControllerWithOpts(ClientOpts{Context: "local"})
+	if err != nil {
+		t.Fatal("NewControllerWithOpts() with context", nil, err)
+	}
+
+	if err := c.Ping(); err != nil {
+		t.Fatal("Ping() with context", nil, err)
+	}
+}
+
+func TestNewControllerWithOptsErrors(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	_, err := NewControllerWithOpts(ClientOpts{
+		Host:     "tcp://build-host:2376",
+		CertPath: "/nonexistent",
+	})
+	if err == nil || !strings.Contains(err.Error(), "TLS certificates") {
+		t.Fatal("error with missing certificates", "loading TLS certificates", err)
+	}
+
+	_, err = NewControllerWithOpts(ClientOpts{Host: "build-host"})
+	if err == nil {
+		t.Fatal("error with invalid host", "error", err)
+	}
+}
@@ -1,24 +1,35 @@
 package docker
 
 import (
-	"archive/tar"
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"io/ioutil"
+	"strconv"
 
 	"github.com/docker/go-connections/nat"
 
 	"github.com/UltimateSoftware/envctl/pkg/container"
-	"github.com/alecthomas/template"
+	"github.com/UltimateSoftware/envctl/pkg/container/dockerfile"
 	"github.com/docker/docker/api/types"
 	docker "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/google/uuid"
 )
 
 func (c *Controller) Create(m container.Metadata) (container.Metadata, error) {
+	if err := c.checkExternalNetworks(m); err != nil {
+		return container.Metadata{}, err
+	}
+
+	if m.Build != nil {
+		base, err := c.buildBaseImage(m)
+		if err != nil {
+			return container.Metadata{}, err
+		}
+
+		m.BaseImage = base
+	}
+
 	img, err := c.buildImage(m)
 	if err != nil {
 		return container.Metadata{}, err
@@ -26,8 +37,20 @@ func (c *Controller) Create(m container.Metadata) (container.Metadata, error) {
 
 	m.ImageID = img
 
-	cpmap := getContainerPortMappings(m.Ports)
-	hpmap := getHostPortMappings(m.Ports)
+	m, err = c.createNetwork(m)
+	if err != nil {
+		return container.Metadata{}, err
+	}
+
+	created, err := c.createServices(m)
+	if err != nil {
+		c.removeNetwork(m)
+		return container.Metadata{}, err
+	}
+
+	m = created
+
+	cpmap, hpmap := getPortMappings(m.Ports)
 
 	ccfg := &docker.Config{
 		User:         m.User,
@@ -36,16 +59,29 @@ func (c *Controller) Create(m container.Metadata) (container.Metadata, error) {
 		OpenStdin:    true,
 		Env:          m.Envs,
 		ExposedPorts: cpmap,
+		Hostname:     m.Hostname,
 	}
 
 	hcfg := &docker.HostConfig{
 		Binds:        make([]string, 1),
+		Mounts:       getMounts(m.Mounts),
 		PortBindings: hpmap,
+		ShmSize:      m.Resources.ShmSize,
+		NetworkMode:  docker.NetworkMode(m.Network),
+		Resources: docker.Resources{
+			NanoCPUs:  m.Resources.NanoCPUs,
+			Memory:    m.Resources.Memory,
+			PidsLimit: m.Resources.PidsLimit,
+		},
 	}
 
 	hcfg.Binds[0] = m.Mount.String()
 
-	ncfg := &network.NetworkingConfig{}
+	ncfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			m.Network: {Aliases: containerAliases(m)},
+		},
+	}
 
 	cnt, err := c.client.ContainerCreate(
 		context.Background(),
@@ -55,30 +91,32 @@ func (c *Controller) Create(m container.Metadata) (container.Metadata, error) {
 		m.BaseName,
 	)
 	if err != nil {
+		c.discard(m)
 		return container.Metadata{}, err
 	}
 
 	m.ID = cnt.ID
+
+	if err := c.connectExternalNetworks(m); err != nil {
+		c.discard(m)
+		return container.Metadata{}, err
+	}
+
 	return m, nil
 }
 
-var dockerfileTpl = `FROM {{ .BaseImage }}
-	VOLUME ["{{ .Mount.Destination }}"]
-	WORKDIR "{{ .Mount.Destination }}"
-	ENTRYPOINT ["{{ .Shell }}"]`
-
 // buildImage will build an image based on the passed in ImageConfig. It returns
 // the name of the built image, as <cfg.BaseName:UUID>, or an error.
 //
 // buildImage blocks until the image build has finished and the API is done
 // streaming the output back.
 func (c *Controller) buildImage(m container.Metadata) (string, error) {
-	dockerfile, err := buildDockerfile(m)
+	df, err := dockerfile.Generate(m)
 	if err != nil {
 		return "", err
 	}
 
-	buildContext, err := getBuildContext(dockerfile)
+	buildContext, err := dockerfile.Context(df)
 	if err != nil {
 		return "", err
 	}
@@ -94,83 +132,60 @@ func (c *Controller) buildImage(m container.Metadata) (string, error) {
 		return "", err
 	}
 
+	defer resp.Body.Close()
+
 	// the read MUST happen, if not the program will continue without waiting
 	// for the build to complete
-	io.Copy(ioutil.Discard, resp.Body)
-	defer resp.Body.Close()
+	if err := c.showProgress(resp.Body); err != nil {
+		return "", err
+	}
 
 	return name, nil
 }
 
-func buildDockerfile(m container.Metadata) (*bytes.Buffer, error) {
-	buf := &bytes.Buffer{}
+func getMounts(mounts []container.Mount) []mount.Mount {
+	mnts := []mount.Mount{}
 
-	tpl, err := template.New("Dockerfile").Parse(dockerfileTpl)
-	if err != nil {
-		return &bytes.Buffer{}, err
-	}
+	for _, m := range mounts {
+		typ := mount.TypeBind
+		switch m.Type {
+		case container.MountVolume:
+			typ = mount.TypeVolume
+		case container.MountTmpfs:
+			typ = mount.TypeTmpfs
+		}
 
-	err = tpl.Execute(buf, &m)
-	if err != nil {
-		return &bytes.Buffer{}, err
+		mnts = append(mnts, mount.Mount{
+			Type:     typ,
+			Source:   m.Source,
+			Target:   m.Destination,
+			ReadOnly: m.ReadOnly,
+		})
 	}
 
-	return buf, nil
+	return mnts
 }
 
-func getBuildContext(raw *bytes.Buffer) (*bytes.Buffer, error) {
-	buf := bytes.NewBuffer([]byte{})
-	wr := tar.NewWriter(buf)
-
-	hdr := &tar.Header{
-		Name: "Dockerfile",
-		Mode: 0600,
-		Size: int64(raw.Len()),
-	}
-
-	if err := wr.WriteHeader(hdr); err != nil {
-		return &bytes.Buffer{}, err
-	}
-
-	if _, err := wr.Write(raw.Bytes()); err != nil {
-		return &bytes.Buffer{}, err
-	}
-
-	padlen := 512 - (buf.Len() % 512)
-	padding := make([]byte, padlen)
-
-	padded := bytes.NewBuffer(append(buf.Bytes(), padding...))
+// getPortMappings returns the ports to expose on the container, and the host
+// ports to bind them to. An empty host port lets Docker pick a free one.
+func getPortMappings(ports []container.PortBinding) (nat.PortSet, nat.PortMap) {
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
 
-	return padded, nil
-}
-
-func getContainerPortMappings(l3map map[string][]int) map[nat.Port]struct{} {
-	mappings := map[nat.Port]struct{}{}
+	for _, p := range ports {
+		port := nat.Port(fmt.Sprintf("%v/%v", p.ContainerPort, p.Protocol))
 
-	for layer, plist := range l3map {
-		for _, p := range plist {
-			pstr := nat.Port(fmt.Sprintf("%v/%v", p, layer))
-			mappings[pstr] = struct{}{}
+		hostPort := ""
+		if p.HostPort != 0 {
+			hostPort = strconv.Itoa(p.HostPort)
 		}
-	}
 
-	return mappings
-}
-
-func getHostPortMappings(l3map map[string][]int) nat.PortMap {
-	mappings := nat.PortMap{}
-
-	for layer, plist := range l3map {
-		for _, p := range plist {
-			pstr := nat.Port(fmt.Sprintf("%v/%v", p, layer))
-
-			bind := nat.PortBinding{
-				HostPort: string(pstr),
-			}
-
-			mappings[pstr] = []nat.PortBinding{bind}
-		}
+		exposed[port] = struct{}{}
+		bindings[port] = append(bindings[port], nat.PortBinding{
+			HostIP:   p.HostIP,
+			HostPort: hostPort,
+		})
 	}
 
-	return mappings
+	return exposed, bindings
 }
@@ -1,80 +1,271 @@
 package docker
 
 import (
-	"archive/tar"
-	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/UltimateSoftware/envctl/pkg/container"
 	"github.com/UltimateSoftware/envctl/test_pkg"
+	"github.com/docker/docker/api/types/mount"
 )
 
-func TestBuildDockerfile(got *testing.T) {
-	t := test_pkg.NewT(got)
-
-	testm := container.Metadata{
-		BaseImage: "scratch",
+func testMetadata() container.Metadata {
+	return container.Metadata{
+		BaseName:  "envctl_foo",
+		BaseImage: "alpine",
+		Shell:     "/bin/sh",
+		Envs:      []string{"FOO=bar"},
 		Mount: container.Mount{
-			Source:      "",
-			Destination: "/test-path",
+			Source:      "/foo/src",
+			Destination: "/foo/mnt",
+		},
+		Ports: []container.PortBinding{
+			{HostPort: 4567, ContainerPort: 4567, Protocol: "tcp"},
 		},
-		Shell: "/testsh",
 	}
+}
+
+func TestCreate(got *testing.T) {
+	t := test_pkg.NewT(got)
 
-	buf, err := buildDockerfile(testm)
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	m, err := d.controller(&t).Create(testMetadata())
 	if err != nil {
-		t.Fatal("errors", nil, err)
+		t.Fatal("Create()", nil, err)
+	}
+
+	cnt, ok := d.containers[m.ID]
+	if !ok {
+		t.Fatal("created container", m.ID, d.containers)
 	}
 
-	expected := `FROM scratch
-	VOLUME ["/test-path"]
-	WORKDIR "/test-path"
-	ENTRYPOINT ["/testsh"]`
+	if m.ImageID != cnt.Image || !d.images[m.ImageID] {
+		t.Fatal("container image", m.ImageID, cnt.Image)
+	}
+
+	if "envctl_foo" != cnt.Name {
+		t.Fatal("container name", "envctl_foo", cnt.Name)
+	}
 
-	actual := buf.String()
-	if expected != actual {
-		t.Fatal("Dockerfile build", expected, actual)
+	if len(cnt.HostConfig.Binds) != 1 || "/foo/src:/foo/mnt" != cnt.HostConfig.Binds[0] {
+		t.Fatal("binds", "/foo/src:/foo/mnt", cnt.HostConfig.Binds)
+	}
+
+	if len(cnt.Config.Env) != 1 || "FOO=bar" != cnt.Config.Env[0] {
+		t.Fatal("env", "FOO=bar", cnt.Config.Env)
+	}
+
+	bindings := cnt.HostConfig.PortBindings["4567/tcp"]
+	if len(bindings) != 1 || "4567" != bindings[0].HostPort {
+		t.Fatal("port bindings", "4567/tcp on 4567", cnt.HostConfig.PortBindings)
+	}
+
+	df := d.builds[0].Files["Dockerfile"]
+	if !strings.HasPrefix(df, "FROM alpine") {
+		t.Fatal("built Dockerfile", "FROM alpine...", df)
 	}
 }
 
-func TestGetBuildContext(got *testing.T) {
+func TestCreateWithMounts(got *testing.T) {
 	t := test_pkg.NewT(got)
 
-	testm := container.Metadata{
-		BaseImage: "scratch",
-		Mount: container.Mount{
-			Source:      "",
-			Destination: "/test-path",
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	meta := testMetadata()
+	meta.Mounts = []container.Mount{
+		{
+			Type:        container.MountBind,
+			Source:      "/home/foo/.ssh",
+			Destination: "/root/.ssh",
+			ReadOnly:    true,
 		},
-		Shell: "/testsh",
+		{Type: container.MountVolume, Source: "m2", Destination: "/root/.m2"},
+		{Type: container.MountTmpfs, Destination: "/tmp"},
 	}
 
-	buf, err := buildDockerfile(testm)
+	m, err := d.controller(&t).Create(meta)
 	if err != nil {
-		t.Fatal("Dockerfile build", "no errors", err)
+		t.Fatal("Create()", nil, err)
 	}
 
-	bldctx, err := getBuildContext(buf)
-	if err != nil {
-		t.Fatal("getBuildContext()", "no errors", err)
+	expected := []mount.Mount{
+		{
+			Type:     mount.TypeBind,
+			Source:   "/home/foo/.ssh",
+			Target:   "/root/.ssh",
+			ReadOnly: true,
+		},
+		{Type: mount.TypeVolume, Source: "m2", Target: "/root/.m2"},
+		{Type: mount.TypeTmpfs, Target: "/tmp"},
 	}
 
-	tarrd := tar.NewReader(bldctx)
-	h, err := tarrd.Next()
-	for err != io.EOF {
-		// The only thing in here should be the Dockerfile. If this is the case,
-		// the for loop will exit before hitting this check again. If it's not,
-		// it will fail because whatever is next won't match this.
-		if h.FileInfo().Name() != "Dockerfile" {
-			t.Fatal("tar header", "Dockerfile", h.FileInfo().Name())
+	actual := d.containers[m.ID].HostConfig.Mounts
+	if len(expected) != len(actual) {
+		t.Fatal("mounts", expected, actual)
+	}
+
+	for i := range expected {
+		if expected[i].Type != actual[i].Type ||
+			expected[i].Source != actual[i].Source ||
+			expected[i].Target != actual[i].Target ||
+			expected[i].ReadOnly != actual[i].ReadOnly {
+
+			t.Fatal("mounts", expected, actual)
 		}
+	}
+
+	// The repo is still bind mounted on its own.
+	binds := d.containers[m.ID].HostConfig.Binds
+	if len(binds) != 1 || "/foo/src:/foo/mnt" != binds[0] {
+		t.Fatal("binds", "/foo/src:/foo/mnt", binds)
+	}
+}
+
+func TestCreateWithBuild(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-build")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
 
-		expected := int64(buf.Len())
-		actual := h.FileInfo().Size()
-		if expected != actual {
-			t.Fatal("tar file size", expected, actual)
+	files := map[string]string{
+		"Dockerfile.dev": "FROM ruby\nCOPY Gemfile /\n",
+		"Gemfile":        "gem 'rails'\n",
+	}
+
+	for name, contents := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal("writing "+name, nil, err)
 		}
+	}
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	meta := testMetadata()
+	meta.BaseImage = ""
+	meta.Build = &container.Build{
+		Context:    dir,
+		Dockerfile: "Dockerfile.dev",
+		Args:       map[string]string{"RUBY_VERSION": "2.5.1"},
+	}
+
+	m, err := d.controller(&t).Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if len(d.builds) != 2 {
+		t.Fatal("number of builds", 2, len(d.builds))
+	}
+
+	base := d.builds[0]
+	if len(base.Tags) != 1 || m.BaseImage != base.Tags[0] {
+		t.Fatal("base image tag", m.BaseImage, base.Tags)
+	}
+
+	if "Dockerfile.dev" != base.Dockerfile {
+		t.Fatal("base Dockerfile", "Dockerfile.dev", base.Dockerfile)
+	}
+
+	if "2.5.1" != base.BuildArgs["RUBY_VERSION"] {
+		t.Fatal("build args", "2.5.1", base.BuildArgs)
+	}
+
+	if files["Gemfile"] != base.Files["Gemfile"] {
+		t.Fatal("build context", files["Gemfile"], base.Files["Gemfile"])
+	}
+
+	df := d.builds[1].Files["Dockerfile"]
+	if !strings.HasPrefix(df, "FROM "+m.BaseImage) {
+		t.Fatal("environment Dockerfile", "FROM "+m.BaseImage, df)
+	}
+}
+
+func TestCreateBuildError(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	d.buildOutput = `{"errorDetail":{"message":"pull access denied"},"error":"pull access denied"}`
+
+	_, err := d.controller(&t).Create(testMetadata())
+	if err == nil || !strings.Contains(err.Error(), "pull access denied") {
+		t.Fatal("Create() error", "pull access denied", err)
+	}
+
+	if len(d.containers) != 0 {
+		t.Fatal("containers", 0, len(d.containers))
+	}
+}
+
+func TestCreateNameConflict(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	// The environment's network is created first, so that's what the name
+	// clashes on.
+	_, err = c.Create(testMetadata())
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatal("Create() error", "already exists", err)
+	}
+
+	if _, ok := d.networks[m.Network]; !ok {
+		t.Fatal("existing network", m.Network, d.networks)
+	}
+
+	if _, ok := d.containers[m.ID]; !ok {
+		t.Fatal("existing container", m.ID, d.containers)
+	}
+}
+
+func TestCreateWithResources(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	meta := testMetadata()
+	meta.Resources = container.Resources{
+		NanoCPUs:  1500000000,
+		Memory:    1 << 30,
+		PidsLimit: 256,
+		ShmSize:   64 << 20,
+	}
+
+	m, err := d.controller(&t).Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	hcfg := d.containers[m.ID].HostConfig
+	actual := container.Resources{
+		NanoCPUs:  hcfg.NanoCpus,
+		Memory:    hcfg.Memory,
+		PidsLimit: hcfg.PidsLimit,
+		ShmSize:   hcfg.ShmSize,
+	}
 
-		h, err = tarrd.Next()
+	if meta.Resources != actual {
+		t.Fatal("resources", meta.Resources, actual)
 	}
 }
@@ -0,0 +1,639 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	volumetypes "github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// fakeDaemon stands in for the Docker daemon, serving the parts of the Engine
+// API that envctl uses. It keeps just enough state to check what a Controller
+// asked for, and the behavior of each endpoint can be swapped out to test
+// error paths.
+type fakeDaemon struct {
+	srv *httptest.Server
+	dir string
+
+	mu         sync.Mutex
+	images     map[string]bool
+	containers map[string]*fakeContainer
+	execs      map[string]*fakeExec
+	volumes    map[string]types.Volume
+	networks   map[string]types.NetworkCreateRequest
+	builds     []fakeBuild
+	removed    []string
+	pulled     []string
+	// unpruned are the removed images whose untagged parents were kept.
+	unpruned []string
+
+	// errors maps "METHOD /path" to an error the endpoint responds with,
+	// as "status message".
+	errors map[string]string
+	// buildOutput is streamed back by every build.
+	buildOutput string
+	// execResult is what every exec does.
+	execResult fakeExec
+	// attachOutput is written back by every attach.
+	attachOutput string
+	// health maps container names to the health status they report, for
+	// containers with a healthcheck. It defaults to "healthy".
+	health map[string]string
+}
+
+type fakeContainer struct {
+	ID     string
+	Name   string
+	Image  string
+	Status string
+	Config struct {
+		Image       string
+		Env         []string
+		Tty         bool
+		Hostname    string
+		Healthcheck *struct {
+			Test     []string
+			Interval int64
+			Timeout  int64
+			Retries  int
+		}
+	}
+	HostConfig struct {
+		Binds        []string
+		Mounts       []mount.Mount
+		PortBindings map[string][]struct{ HostIP, HostPort string }
+		NanoCpus     int64
+		Memory       int64
+		PidsLimit    int64
+		ShmSize      int64
+		NetworkMode  string
+	}
+	NetworkingConfig struct {
+		EndpointsConfig map[string]struct{ Aliases []string }
+	}
+	// Joined maps the networks the container joined after it was created to
+	// its aliases on them.
+	Joined map[string][]string
+}
+
+type fakeExec struct {
+	Cmd      []string
+	Tty      bool
+	Output   string
+	ExitCode int
+}
+
+type fakeBuild struct {
+	Tags       []string
+	Dockerfile string
+	BuildArgs  map[string]string
+	Files      map[string]string
+}
+
+func newFakeDaemon(t *test_pkg.T) *fakeDaemon {
+	d := &fakeDaemon{
+		images:      map[string]bool{},
+		containers:  map[string]*fakeContainer{},
+		execs:       map[string]*fakeExec{},
+		volumes:     map[string]types.Volume{},
+		networks:    map[string]types.NetworkCreateRequest{},
+		errors:      map[string]string{},
+		health:      map[string]string{},
+		buildOutput: `{"stream":"Successfully built\n"}`,
+	}
+
+	// The daemon listens on a unix socket like the real one does by default.
+	// The client only upgrades connections to streaming endpoints to TLS
+	// over TCP.
+	dir, err := ioutil.TempDir("", "envctl-daemon")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+
+	l, err := net.Listen("unix", filepath.Join(dir, "docker.sock"))
+	if err != nil {
+		t.Fatal("listening on socket", nil, err)
+	}
+
+	d.dir = dir
+	d.srv = httptest.NewUnstartedServer(http.HandlerFunc(d.serve))
+	d.srv.Listener = l
+	d.srv.Start()
+
+	return d
+}
+
+func (d *fakeDaemon) Close() {
+	d.srv.Close()
+	os.RemoveAll(d.dir)
+}
+
+// controller returns a Controller talking to the daemon, with its output
+// going to /dev/null and nothing coming in on stdin.
+func (d *fakeDaemon) controller(t *test_pkg.T) *Controller {
+	socket := filepath.Join(d.dir, "docker.sock")
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+
+	cli, err := client.NewClient("unix://"+socket, "1.25",
+		&http.Client{Transport: &http.Transport{DialContext: dial}}, nil)
+	if err != nil {
+		t.Fatal("creating client", nil, err)
+	}
+
+	c := NewControllerWithClient(cli)
+
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal("opening /dev/null", nil, err)
+	}
+
+	c.stdin = termStream{stream: null, fd: null.Fd()}
+	c.stdout = termStream{stream: null, fd: null.Fd()}
+	c.stderr = termStream{stream: null, fd: null.Fd()}
+
+	return c
+}
+
+// capture points the controller's stdout at a temp file and returns a
+// function that reads back everything written to it, and then removes it.
+func (d *fakeDaemon) capture(t *test_pkg.T, c *Controller) func() string {
+	out, err := ioutil.TempFile("", "envctl-daemon")
+	if err != nil {
+		t.Fatal("creating temp file", nil, err)
+	}
+
+	c.stdout = termStream{stream: out, fd: out.Fd()}
+
+	return func() string {
+		defer os.Remove(out.Name())
+		defer out.Close()
+
+		raw, err := ioutil.ReadFile(out.Name())
+		if err != nil {
+			t.Fatal("reading output", nil, err)
+		}
+
+		return string(raw)
+	}
+}
+
+func (d *fakeDaemon) serve(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := r.URL.Path
+	if i := strings.Index(path[1:], "/"); strings.HasPrefix(path, "/v1.") {
+		path = path[i+1:]
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	if e, ok := d.errors[r.Method+" "+path]; ok {
+		var status int
+		var msg string
+		fmt.Sscanf(e, "%d", &status)
+		msg = strings.TrimSpace(strings.TrimPrefix(e, fmt.Sprint(status)))
+		d.fail(w, status, msg)
+		return
+	}
+
+	switch {
+	case path == "/_ping":
+		io.WriteString(w, "OK")
+
+	case r.Method == "POST" && path == "/build":
+		d.build(w, r)
+
+	case r.Method == "POST" && path == "/images/create":
+		name := r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+		d.images[name] = true
+		d.pulled = append(d.pulled, name)
+		io.WriteString(w, `{"status":"Pulling from library"}`)
+
+	case r.Method == "GET" && path == "/images/json":
+		d.listImages(w, r)
+
+	case r.Method == "GET" && parts[0] == "images" && len(parts) >= 3:
+		name := strings.Join(parts[1:len(parts)-1], "/")
+		if !d.images[name] {
+			d.fail(w, http.StatusNotFound, "No such image: "+name)
+			return
+		}
+
+		json.NewEncoder(w).Encode(types.ImageInspect{ID: name})
+
+	case r.Method == "DELETE" && parts[0] == "images":
+		name := strings.Join(parts[1:], "/")
+		if !d.images[name] {
+			d.fail(w, http.StatusNotFound, "No such image: "+name)
+			return
+		}
+
+		delete(d.images, name)
+		d.removed = append(d.removed, name)
+		if r.URL.Query().Get("noprune") == "1" {
+			d.unpruned = append(d.unpruned, name)
+		}
+		json.NewEncoder(w).Encode([]types.ImageDelete{{Deleted: name}})
+
+	case r.Method == "POST" && path == "/containers/create":
+		d.createContainer(w, r)
+
+	case parts[0] == "containers":
+		d.container(w, r, parts[1], parts[2:])
+
+	case r.Method == "POST" && path == "/volumes/create":
+		var body volumetypes.VolumesCreateBody
+		json.NewDecoder(r.Body).Decode(&body)
+
+		v, ok := d.volumes[body.Name]
+		if !ok {
+			v = types.Volume{Name: body.Name, Driver: "local", Labels: body.Labels}
+			d.volumes[v.Name] = v
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(v)
+
+	case r.Method == "GET" && path == "/volumes":
+		args, _ := filters.FromParam(r.URL.Query().Get("filters"))
+
+		body := volumetypes.VolumesListOKBody{Volumes: []*types.Volume{}}
+		for _, v := range d.volumes {
+			v := v
+			if args.MatchKVList("label", v.Labels) {
+				body.Volumes = append(body.Volumes, &v)
+			}
+		}
+
+		json.NewEncoder(w).Encode(body)
+
+	case r.Method == "DELETE" && parts[0] == "volumes" && len(parts) == 2:
+		if _, ok := d.volumes[parts[1]]; !ok {
+			d.fail(w, http.StatusNotFound, "get "+parts[1]+": no such volume")
+			return
+		}
+
+		for _, cnt := range d.containers {
+			for _, m := range cnt.HostConfig.Mounts {
+				if m.Type == mount.TypeVolume && m.Source == parts[1] {
+					d.fail(w, http.StatusConflict, "remove "+parts[1]+": volume is in use")
+					return
+				}
+			}
+		}
+
+		delete(d.volumes, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "POST" && path == "/networks/create":
+		var body types.NetworkCreateRequest
+		json.NewDecoder(r.Body).Decode(&body)
+
+		if _, ok := d.networks[body.Name]; ok {
+			d.fail(w, http.StatusConflict,
+				fmt.Sprintf("network with name %v already exists", body.Name))
+			return
+		}
+
+		d.networks[body.Name] = body
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(types.NetworkCreateResponse{ID: body.Name})
+
+	case r.Method == "GET" && parts[0] == "networks" && len(parts) == 2:
+		body, ok := d.networks[parts[1]]
+		if !ok {
+			d.fail(w, http.StatusNotFound, "network "+parts[1]+" not found")
+			return
+		}
+
+		json.NewEncoder(w).Encode(types.NetworkResource{
+			Name:   body.Name,
+			ID:     body.Name,
+			Driver: body.Driver,
+		})
+
+	case r.Method == "POST" && parts[0] == "networks" && len(parts) == 3 &&
+		parts[2] == "connect":
+
+		if _, ok := d.networks[parts[1]]; !ok {
+			d.fail(w, http.StatusNotFound, "network "+parts[1]+" not found")
+			return
+		}
+
+		var body types.NetworkConnect
+		json.NewDecoder(r.Body).Decode(&body)
+
+		cnt, ok := d.containers[body.Container]
+		if !ok {
+			d.fail(w, http.StatusNotFound, "No such container: "+body.Container)
+			return
+		}
+
+		if cnt.Joined == nil {
+			cnt.Joined = map[string][]string{}
+		}
+
+		cnt.Joined[parts[1]] = body.EndpointConfig.Aliases
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == "DELETE" && parts[0] == "networks" && len(parts) == 2:
+		if _, ok := d.networks[parts[1]]; !ok {
+			d.fail(w, http.StatusNotFound, "network "+parts[1]+" not found")
+			return
+		}
+
+		for _, cnt := range d.containers {
+			if _, ok := cnt.Joined[parts[1]]; ok ||
+				cnt.HostConfig.NetworkMode == parts[1] {
+
+				d.fail(w, http.StatusForbidden,
+					"error while removing network: network has active endpoints")
+				return
+			}
+		}
+
+		delete(d.networks, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == "GET" && parts[0] == "exec" && len(parts) == 3:
+		exec, ok := d.execs[parts[1]]
+		if !ok {
+			d.fail(w, http.StatusNotFound, "No such exec instance")
+			return
+		}
+
+		json.NewEncoder(w).Encode(types.ContainerExecInspect{
+			ExecID:   parts[1],
+			ExitCode: exec.ExitCode,
+		})
+
+	case r.Method == "POST" && parts[0] == "exec" && len(parts) == 3:
+		exec, ok := d.execs[parts[1]]
+		if !ok {
+			d.fail(w, http.StatusNotFound, "No such exec instance")
+			return
+		}
+
+		conn := d.hijack(w)
+		defer conn.Close()
+
+		if exec.Tty {
+			io.WriteString(conn, exec.Output)
+		} else {
+			stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write([]byte(exec.Output))
+		}
+
+	default:
+		d.fail(w, http.StatusNotFound, "page not found")
+	}
+}
+
+func (d *fakeDaemon) build(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	b := fakeBuild{
+		Tags:       q["t"],
+		Dockerfile: q.Get("dockerfile"),
+		BuildArgs:  map[string]string{},
+		Files:      map[string]string{},
+	}
+
+	if args := q.Get("buildargs"); args != "" {
+		json.Unmarshal([]byte(args), &b.BuildArgs)
+	}
+
+	tr := tar.NewReader(r.Body)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+
+		raw, _ := ioutil.ReadAll(tr)
+		b.Files[hdr.Name] = string(raw)
+	}
+
+	d.builds = append(d.builds, b)
+
+	if !strings.Contains(d.buildOutput, `"error"`) {
+		for _, tag := range b.Tags {
+			d.images[tag] = true
+		}
+	}
+
+	io.WriteString(w, d.buildOutput)
+}
+
+func (d *fakeDaemon) listImages(w http.ResponseWriter, r *http.Request) {
+	args, _ := filters.FromParam(r.URL.Query().Get("filters"))
+
+	imgs := []types.ImageSummary{}
+	for name := range d.images {
+		if args.Len() == 0 || args.ExactMatch("reference", name) {
+			imgs = append(imgs, types.ImageSummary{ID: name})
+		}
+	}
+
+	json.NewEncoder(w).Encode(imgs)
+}
+
+func (d *fakeDaemon) createContainer(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	for _, cnt := range d.containers {
+		if name != "" && cnt.Name == name {
+			d.fail(w, http.StatusConflict,
+				fmt.Sprintf("Conflict. The name %q is already in use", name))
+			return
+		}
+	}
+
+	// The container's config is at the top level of the body, alongside its
+	// host config.
+	raw, _ := ioutil.ReadAll(r.Body)
+
+	var cnt fakeContainer
+	json.Unmarshal(raw, &cnt.Config)
+	json.Unmarshal(raw, &cnt)
+
+	cnt.ID = fmt.Sprintf("cnt%v", len(d.containers)+1)
+	cnt.Name = name
+	cnt.Image = cnt.Config.Image
+	cnt.Status = "created"
+
+	if !d.images[cnt.Image] {
+		d.fail(w, http.StatusNotFound, "No such image: "+cnt.Image)
+		return
+	}
+
+	d.containers[cnt.ID] = &cnt
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"Id": cnt.ID})
+}
+
+func (d *fakeDaemon) container(
+	w http.ResponseWriter,
+	r *http.Request,
+	id string,
+	action []string,
+) {
+	cnt, ok := d.containers[id]
+	if !ok {
+		d.fail(w, http.StatusNotFound, "No such container: "+id)
+		return
+	}
+
+	if len(action) == 0 {
+		if r.Method != "DELETE" {
+			d.fail(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		delete(d.containers, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch action[0] {
+	case "json":
+		// Ports are only published while the container is running, and the
+		// ones without a host port get one picked for them.
+		ports := nat.PortMap{}
+		if cnt.Status == "running" {
+			next := 32768
+			for port, bindings := range cnt.HostConfig.PortBindings {
+				for _, b := range bindings {
+					if b.HostPort == "" {
+						next++
+						b.HostPort = fmt.Sprint(next)
+					}
+
+					ports[nat.Port(port)] = append(ports[nat.Port(port)],
+						nat.PortBinding{HostIP: b.HostIP, HostPort: b.HostPort})
+				}
+			}
+		}
+
+		state := map[string]interface{}{
+			"Status":  cnt.Status,
+			"Running": cnt.Status == "running",
+		}
+
+		if cnt.Config.Healthcheck != nil && cnt.Status == "running" {
+			health, ok := d.health[cnt.Name]
+			if !ok {
+				health = "healthy"
+			}
+
+			state["Health"] = types.Health{
+				Status: health,
+				Log:    []*types.HealthcheckResult{{Output: "check output\n"}},
+			}
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Id":    cnt.ID,
+			"Image": cnt.Image,
+			"State": state,
+			"NetworkSettings": map[string]interface{}{
+				"Ports": ports,
+			},
+		})
+
+	case "start":
+		cnt.Status = "running"
+		w.WriteHeader(http.StatusNoContent)
+
+	case "stop":
+		cnt.Status = "exited"
+		w.WriteHeader(http.StatusNoContent)
+
+	case "resize":
+		w.WriteHeader(http.StatusOK)
+
+	case "exec":
+		var cfg types.ExecConfig
+		json.NewDecoder(r.Body).Decode(&cfg)
+
+		exec := d.execResult
+		exec.Cmd = cfg.Cmd
+		exec.Tty = cfg.Tty
+
+		execID := fmt.Sprintf("exec%v", len(d.execs)+1)
+		d.execs[execID] = &exec
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"Id": execID})
+
+	case "attach":
+		conn := d.hijack(w)
+		defer conn.Close()
+
+		io.WriteString(conn, d.attachOutput)
+
+	default:
+		d.fail(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// hijack takes over the connection the way the daemon does for streaming
+// endpoints.
+func (d *fakeDaemon) hijack(w http.ResponseWriter) io.WriteCloser {
+	conn, _, _ := w.(http.Hijacker).Hijack()
+	io.WriteString(conn, "HTTP/1.1 101 UPGRADED\r\n"+
+		"Content-Type: application/vnd.docker.raw-stream\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Upgrade: tcp\r\n\r\n")
+
+	return conn
+}
+
+func (d *fakeDaemon) fail(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": msg})
+}
+
+// TestFakeDaemon makes sure the stand-in behaves like the daemon as far as
+// the client can tell, so that the other tests can trust it.
+func TestFakeDaemon(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	if err := c.Ping(); err != nil {
+		t.Fatal("Ping()", nil, err)
+	}
+
+	_, err := c.client.ContainerInspect(context.Background(), "nope")
+	if !client.IsErrContainerNotFound(err) {
+		t.Fatal("inspecting missing container", "not found error", err)
+	}
+}
@@ -1,8 +1,10 @@
 package docker
 
 import (
+	"context"
 	"os"
 
+	"github.com/UltimateSoftware/envctl/pkg/container"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/term"
 )
@@ -21,13 +23,34 @@ type termStream struct {
 	fd     uintptr
 }
 
-// NewController returns a `*Controller` with stdin, stdout and stderr initialized.
+// init registers Docker as a runtime. Its options are the fields of
+// ClientOpts: "host", "cert_path", "api_version" and "context".
+func init() {
+	container.Register("docker", func(opts container.Options) (container.Controller, error) {
+		return NewControllerWithOpts(ClientOpts{
+			Host:       opts["host"],
+			CertPath:   opts["cert_path"],
+			APIVersion: opts["api_version"],
+			Context:    opts["context"],
+		})
+	})
+}
+
+// NewController returns a `*Controller` with stdin, stdout and stderr
+// initialized, talking to the Docker daemon set up in the environment, the
+// same way the docker CLI does.
 func NewController() (*Controller, error) {
 	cli, err := client.NewEnvClient()
 	if err != nil {
 		return nil, err
 	}
 
+	return NewControllerWithClient(cli), nil
+}
+
+// NewControllerWithClient returns a `*Controller` like NewController, but
+// talking to the Docker daemon through the given client.
+func NewControllerWithClient(cli *client.Client) *Controller {
 	stdinfd, _ := term.GetFdInfo(os.Stdin)
 	stdoutfd, _ := term.GetFdInfo(os.Stdout)
 	stderrfd, _ := term.GetFdInfo(os.Stderr)
@@ -37,5 +60,11 @@ func NewController() (*Controller, error) {
 		stdin:  termStream{stream: os.Stdin, fd: stdinfd},
 		stdout: termStream{stream: os.Stdout, fd: stdoutfd},
 		stderr: termStream{stream: os.Stderr, fd: stderrfd},
-	}, nil
+	}
+}
+
+// Ping checks that the Docker daemon is reachable.
+func (c *Controller) Ping() error {
+	_, err := c.client.Ping(context.Background())
+	return err
 }
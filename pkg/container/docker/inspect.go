@@ -0,0 +1,87 @@
+package docker
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+)
+
+// Inspect asks the Docker daemon what the container with the given metadata
+// and its image are up to. A container or image that's gone isn't treated as
+// an error, it's reported in the returned state.
+func (c *Controller) Inspect(m container.Metadata) (container.State, error) {
+	ctx := context.Background()
+
+	cnt, err := c.client.ContainerInspect(ctx, m.ID)
+	if client.IsErrContainerNotFound(err) {
+		return container.State{Status: container.StatusMissing}, nil
+	}
+
+	if err != nil {
+		return container.State{}, err
+	}
+
+	_, _, err = c.client.ImageInspectWithRaw(ctx, m.ImageID)
+	if client.IsErrImageNotFound(err) {
+		return container.State{Status: container.StatusImageMissing}, nil
+	}
+
+	if err != nil {
+		return container.State{}, err
+	}
+
+	state := container.State{
+		ExitCode: cnt.State.ExitCode,
+	}
+
+	if cnt.NetworkSettings != nil {
+		state.Ports = getPortBindings(cnt.NetworkSettings.Ports)
+	}
+
+	switch {
+	case cnt.State.Running:
+		state.Status = container.StatusRunning
+	case cnt.State.Status == "created":
+		state.Status = container.StatusCreated
+	default:
+		state.Status = container.StatusExited
+	}
+
+	return state, nil
+}
+
+// getPortBindings turns the ports Docker published into bindings, sorted by
+// container port.
+func getPortBindings(ports nat.PortMap) []container.PortBinding {
+	bindings := []container.PortBinding{}
+
+	for port, pbs := range ports {
+		for _, pb := range pbs {
+			hostPort, err := strconv.Atoi(pb.HostPort)
+			if err != nil {
+				continue
+			}
+
+			bindings = append(bindings, container.PortBinding{
+				HostIP:        pb.HostIP,
+				HostPort:      hostPort,
+				ContainerPort: port.Int(),
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].ContainerPort != bindings[j].ContainerPort {
+			return bindings[i].ContainerPort < bindings[j].ContainerPort
+		}
+
+		return bindings[i].Protocol < bindings[j].Protocol
+	})
+
+	return bindings
+}
@@ -0,0 +1,104 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestInspect(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	steps := []struct {
+		name     string
+		fn       func()
+		expected container.Status
+	}{
+		{"create", func() {}, container.StatusCreated},
+		{"start", func() { c.Start(m) }, container.StatusRunning},
+		{"stop", func() { c.Stop(m) }, container.StatusExited},
+		{"rmi", func() { delete(d.images, m.ImageID) }, container.StatusImageMissing},
+		{"rm", func() { delete(d.containers, m.ID) }, container.StatusMissing},
+	}
+
+	for _, step := range steps {
+		step.fn()
+
+		state, err := c.Inspect(m)
+		if err != nil {
+			t.Fatal("Inspect() after "+step.name, nil, err)
+		}
+
+		if step.expected != state.Status {
+			t.Fatal("status after "+step.name, step.expected, state.Status)
+		}
+	}
+}
+
+func TestInspectPorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	meta := testMetadata()
+	meta.Ports = []container.PortBinding{
+		{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{ContainerPort: 3000, Protocol: "tcp"},
+	}
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	bindings := d.containers[m.ID].HostConfig.PortBindings
+	if "127.0.0.1" != bindings["80/tcp"][0].HostIP ||
+		"8080" != bindings["80/tcp"][0].HostPort ||
+		"" != bindings["3000/tcp"][0].HostPort {
+
+		t.Fatal("port bindings", meta.Ports, bindings)
+	}
+
+	state, _ := c.Inspect(m)
+	if 0 != len(state.Ports) {
+		t.Fatal("ports before starting", nil, state.Ports)
+	}
+
+	if err := c.Start(m); err != nil {
+		t.Fatal("Start()", nil, err)
+	}
+
+	state, err = c.Inspect(m)
+	if err != nil {
+		t.Fatal("Inspect()", nil, err)
+	}
+
+	expected := []container.PortBinding{
+		{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{HostPort: 32769, ContainerPort: 3000, Protocol: "tcp"},
+	}
+
+	if len(expected) != len(state.Ports) {
+		t.Fatal("published ports", expected, state.Ports)
+	}
+
+	for i := range expected {
+		if expected[i] != state.Ports[i] {
+			t.Fatal("published ports", expected, state.Ports)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// createNetwork creates the container's own network, which its services are
+// on as well. Unlike Docker's default bridge network, it lets containers
+// reach each other by name.
+func (c *Controller) createNetwork(m container.Metadata) (container.Metadata, error) {
+	_, err := c.client.NetworkCreate(
+		context.Background(),
+		m.BaseName,
+		types.NetworkCreate{
+			CheckDuplicate: true,
+			Driver:         "bridge",
+		},
+	)
+	if err != nil {
+		return container.Metadata{}, err
+	}
+
+	m.Network = m.BaseName
+	return m, nil
+}
+
+// removeNetwork removes the container's own network, if it has one and it's
+// still there. External networks are left alone.
+func (c *Controller) removeNetwork(m container.Metadata) error {
+	if m.Network == "" {
+		return nil
+	}
+
+	_, err := c.client.NetworkInspect(context.Background(), m.Network)
+	if client.IsErrNetworkNotFound(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return c.client.NetworkRemove(context.Background(), m.Network)
+}
+
+// checkExternalNetworks makes sure every external network exists, so that
+// nothing gets built or created for a container that can't join them.
+func (c *Controller) checkExternalNetworks(m container.Metadata) error {
+	for _, name := range m.ExternalNetworks {
+		_, err := c.client.NetworkInspect(context.Background(), name)
+		if client.IsErrNetworkNotFound(err) {
+			return fmt.Errorf("external network %v doesn't exist", name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// connectExternalNetworks has the container join every external network,
+// under the same names it has on its own network. Docker can only attach a
+// container to a single network when creating it, so the rest have to be
+// joined afterwards.
+func (c *Controller) connectExternalNetworks(m container.Metadata) error {
+	for _, name := range m.ExternalNetworks {
+		err := c.client.NetworkConnect(
+			context.Background(),
+			name,
+			m.ID,
+			&network.EndpointSettings{Aliases: containerAliases(m)},
+		)
+		if err != nil {
+			return fmt.Errorf("error joining network %v: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// containerAliases are the names the container can be reached at on its
+// networks, besides its container name.
+func containerAliases(m container.Metadata) []string {
+	aliases := []string{}
+	if m.Hostname != "" {
+		aliases = append(aliases, m.Hostname)
+	}
+
+	return append(aliases, m.Aliases...)
+}
+
+// discard removes whatever was created for the container before creating it
+// failed part of the way through. Errors are ignored, since there's already
+// one to report.
+func (c *Controller) discard(m container.Metadata) {
+	if m.ID != "" {
+		c.client.ContainerRemove(
+			context.Background(),
+			m.ID,
+			types.ContainerRemoveOptions{RemoveVolumes: true, Force: true},
+		)
+	}
+
+	c.removeServices(m)
+	c.removeNetwork(m)
+}
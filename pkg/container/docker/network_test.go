@@ -0,0 +1,140 @@
+package docker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	"github.com/docker/docker/api/types"
+)
+
+func TestCreateNetwork(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	meta := testMetadata()
+	meta.Hostname = "app"
+	meta.Aliases = []string{"api"}
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if _, ok := d.networks["envctl_foo"]; !ok || "envctl_foo" != m.Network {
+		t.Fatal("network", "envctl_foo", d.networks)
+	}
+
+	if "bridge" != d.networks["envctl_foo"].Driver {
+		t.Fatal("network driver", "bridge", d.networks["envctl_foo"].Driver)
+	}
+
+	cnt := d.containers[m.ID]
+	if "envctl_foo" != cnt.HostConfig.NetworkMode {
+		t.Fatal("container network", "envctl_foo", cnt.HostConfig.NetworkMode)
+	}
+
+	if "app" != cnt.Config.Hostname {
+		t.Fatal("hostname", "app", cnt.Config.Hostname)
+	}
+
+	aliases := cnt.NetworkingConfig.EndpointsConfig["envctl_foo"].Aliases
+	if strings.Join(aliases, ",") != "app,api" {
+		t.Fatal("aliases", []string{"app", "api"}, aliases)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if len(d.networks) != 0 {
+		t.Fatal("networks after Remove()", nil, d.networks)
+	}
+}
+
+func TestCreateExternalNetworks(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+	c.client.NetworkCreate(context.Background(), "compose_default", types.NetworkCreate{})
+
+	meta := testMetadata()
+	meta.Hostname = "app"
+	meta.ExternalNetworks = []string{"compose_default"}
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	aliases, ok := d.containers[m.ID].Joined["compose_default"]
+	if !ok || strings.Join(aliases, ",") != "app" {
+		t.Fatal("aliases on external network", []string{"app"}, aliases)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if _, ok := d.networks["compose_default"]; !ok || len(d.networks) != 1 {
+		t.Fatal("networks after Remove()", "compose_default", d.networks)
+	}
+}
+
+func TestCreateMissingExternalNetwork(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	meta := testMetadata()
+	meta.ExternalNetworks = []string{"nope"}
+
+	_, err := d.controller(&t).Create(meta)
+	if err == nil || "external network nope doesn't exist" != err.Error() {
+		t.Fatal("Create() error", "external network nope doesn't exist", err)
+	}
+
+	if len(d.builds) != 0 || len(d.networks) != 0 {
+		t.Fatal("builds and networks", nil, d.builds)
+	}
+}
+
+func TestCreateJoinNetworkError(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+	c.client.NetworkCreate(context.Background(), "compose_default", types.NetworkCreate{})
+
+	d.errors["POST /networks/compose_default/connect"] = "500 boom"
+
+	meta := testMetadata()
+	meta.Services = testServices()
+	meta.ExternalNetworks = []string{"compose_default"}
+
+	_, err := c.Create(meta)
+	if err == nil || !strings.Contains(err.Error(), "error joining network compose_default") {
+		t.Fatal("Create() error", "error joining network compose_default", err)
+	}
+
+	// Everything created for the environment is cleaned up, but the external
+	// network is left alone.
+	if len(d.containers) != 0 {
+		t.Fatal("containers after Create()", nil, d.containers)
+	}
+
+	if _, ok := d.networks["compose_default"]; !ok || len(d.networks) != 1 {
+		t.Fatal("networks after Create()", "compose_default", d.networks)
+	}
+}
@@ -0,0 +1,28 @@
+package docker
+
+import (
+	"io"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/term"
+)
+
+// showProgress renders the stream of JSON messages the Docker daemon sends
+// back while building or pulling an image, like build steps and layer
+// downloads, onto the controller's stdout. It blocks until the stream is done.
+//
+// Errors don't fail the request itself. They're only reported as a message
+// in the stream, so if there's one, it's returned as an error.
+func (c *Controller) showProgress(r io.Reader) error {
+	return showProgress(r, c.stdout)
+}
+
+func showProgress(r io.Reader, out termStream) error {
+	return jsonmessage.DisplayJSONMessagesStream(
+		r,
+		out.stream,
+		out.fd,
+		term.IsTerminal(out.fd),
+		nil,
+	)
+}
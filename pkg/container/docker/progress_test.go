@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestShowProgress(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	out, err := ioutil.TempFile("", "envctl-progress")
+	if err != nil {
+		t.Fatal("creating temp file", nil, err)
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	stream := `{"stream":"Step 1/2 : FROM alpine\n"}
+{"status":"Pulling from library/alpine","id":"latest"}
+{"stream":"Step 2/2 : RUN make\n"}
+`
+
+	err = showProgress(strings.NewReader(stream), termStream{
+		stream: out,
+		fd:     out.Fd(),
+	})
+	if err != nil {
+		t.Fatal("showProgress()", nil, err)
+	}
+
+	raw, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal("reading output", nil, err)
+	}
+
+	for _, expected := range []string{
+		"Step 1/2 : FROM alpine",
+		"latest: Pulling from library/alpine",
+		"Step 2/2 : RUN make",
+	} {
+		if !strings.Contains(string(raw), expected) {
+			t.Fatal("progress output", expected, string(raw))
+		}
+	}
+}
+
+func TestShowProgressError(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	stream := `{"stream":"Step 1/2 : FROM alpine\n"}
+{"errorDetail":{"message":"manifest for alpine:nope not found"},"error":"manifest for alpine:nope not found"}
+`
+
+	devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal("opening "+os.DevNull, nil, err)
+	}
+	defer devnull.Close()
+
+	err = showProgress(strings.NewReader(stream), termStream{
+		stream: devnull,
+		fd:     devnull.Fd(),
+	})
+
+	expected := "manifest for alpine:nope not found"
+	if err == nil || err.Error() != expected {
+		t.Fatal("build error", expected, err)
+	}
+}
@@ -2,50 +2,70 @@ package docker
 
 import (
 	"context"
-	"time"
 
 	"github.com/UltimateSoftware/envctl/pkg/container"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
 )
 
-// Remove removes the container with the given metadata.
+// Remove removes the container with the given metadata, along with its
+// services and its own network. A container that's already gone, like one
+// removed outside of envctl, doesn't keep the rest from being removed.
 func (c *Controller) Remove(m container.Metadata) error {
 	cnt, err := c.client.ContainerInspect(context.Background(), m.ID)
-	if err != nil {
+	missing := client.IsErrContainerNotFound(err)
+	if err != nil && !missing {
 		return err
 	}
 
-	if cnt.ContainerJSONBase.State.Running {
-		timeout := 10 * time.Second
-		err := c.client.ContainerStop(context.Background(), m.ID,
-			&timeout)
+	if !missing && cnt.ContainerJSONBase.State.Running {
+		if err := c.Stop(m); err != nil {
+			return err
+		}
+	}
+
+	// Baked in bootstrap steps live in the image's parent layers. Leaving them
+	// around lets the next build of the same steps use them as its cache.
+	if err := c.removeImage(m.ImageID, len(m.Bootstrap) == 0); err != nil {
+		return err
+	}
+
+	// When the base image was built from a Dockerfile, it belongs to the
+	// environment just as much as the image built on top of it.
+	if m.Build != nil {
+		if err := c.removeImage(m.BaseImage, true); err != nil {
+			return err
+		}
+	}
+
+	if !missing {
+		err = c.client.ContainerRemove(
+			context.Background(),
+			m.ID,
+			types.ContainerRemoveOptions{
+				RemoveVolumes: true,
+				Force:         true,
+			},
+		)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = c.removeImage(m.ImageID)
-	if err != nil {
+	if err := c.removeServices(m); err != nil {
 		return err
 	}
 
-	return c.client.ContainerRemove(
-		context.Background(),
-		m.ID,
-		types.ContainerRemoveOptions{
-			RemoveVolumes: true,
-			Force:         true,
-		},
-	)
+	return c.removeNetwork(m)
 }
 
-func (c *Controller) removeImage(name string) error {
+func (c *Controller) removeImage(name string, prune bool) error {
 	args := filters.NewArgs()
 	args.Add("reference", name)
 
 	rmopts := types.ImageRemoveOptions{
-		PruneChildren: true,
+		PruneChildren: prune,
 		Force:         true,
 	}
 
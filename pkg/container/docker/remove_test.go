@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestRemove(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.Start(m); err != nil {
+		t.Fatal("Start()", nil, err)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if _, ok := d.containers[m.ID]; ok {
+		t.Fatal("container after Remove()", nil, d.containers[m.ID])
+	}
+
+	if d.images[m.ImageID] {
+		t.Fatal("image after Remove()", false, true)
+	}
+
+	if len(d.unpruned) != 0 {
+		t.Fatal("images removed without pruning", nil, d.unpruned)
+	}
+}
+
+func TestRemoveBakedBootstrap(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	meta := testMetadata()
+	meta.Bootstrap = []string{"make deps"}
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	// The layers of the baked in steps are kept for the next build to use.
+	if len(d.unpruned) != 1 || m.ImageID != d.unpruned[0] {
+		t.Fatal("images removed without pruning", []string{m.ImageID}, d.unpruned)
+	}
+}
+
+func TestRemoveMissingContainer(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	meta := testMetadata()
+	meta.Services = testServices()
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	// The container was removed outside of envctl, but its image, services
+	// and network are still there.
+	delete(d.containers, m.ID)
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if d.images[m.ImageID] {
+		t.Fatal("image after Remove()", false, true)
+	}
+
+	if len(d.containers) != 0 {
+		t.Fatal("services after Remove()", nil, d.containers)
+	}
+
+	if len(d.networks) != 0 {
+		t.Fatal("networks after Remove()", nil, d.networks)
+	}
+}
+
+func TestRemoveImageError(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	d.errors["DELETE /images/"+m.ImageID] = "409 image is being used"
+
+	if err := c.Remove(m); err == nil {
+		t.Fatal("Remove() error", "image is being used", err)
+	}
+
+	// The container is only removed once its images are gone, so that
+	// removing it again can finish the job.
+	if _, ok := d.containers[m.ID]; !ok {
+		t.Fatal("container after failed Remove()", m.ID, nil)
+	}
+}
@@ -3,79 +3,108 @@ package docker
 import (
 	"context"
 	"io"
-	"os"
+	"time"
 
 	"github.com/UltimateSoftware/envctl/pkg/container"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 // Run runs the given command array on the container with the given metadata.
-func (c *Controller) Run(m container.Metadata, cmd []string) (err error) {
-	ctx, cancel := context.WithCancel(context.Background())
+//
+// Without a TTY, the command's stdout and stderr are demultiplexed onto the
+// controller's stdout and stderr. Once the command is done, Run inspects its
+// exit status and returns a *container.ExitError if it's non-zero.
+func (c *Controller) Run(
+	m container.Metadata,
+	cmd []string,
+	opts container.RunOpts,
+) error {
+	ctx := context.Background()
 
-	c.mirrorContainerTTY(m.ID)
+	tty := opts.TTY
+	stdin := opts.Stdin && !tty
+	if tty {
+		c.mirrorContainerTTY(m.ID)
+	}
 
-	err = c.client.ContainerStart(
+	err := c.client.ContainerStart(
 		ctx,
 		m.ID,
 		types.ContainerStartOptions{},
 	)
 	if err != nil {
-		cancel()
 		return err
 	}
 
 	cfg := types.ExecConfig{
+		AttachStdin:  stdin,
 		AttachStderr: true,
 		AttachStdout: true,
 		Cmd:          cmd,
 		Detach:       false,
-		Tty:          true,
+		Tty:          tty,
 	}
 
 	resp, err := c.client.ContainerExecCreate(ctx, m.ID, cfg)
 	if err != nil {
-		cancel()
 		return err
 	}
 
+	// Attaching to the exec is also what starts it, so there's no need for a
+	// separate call to ContainerExecStart.
 	hijacked, err := c.client.ContainerExecAttach(ctx, resp.ID, cfg)
 	if err != nil {
-		cancel()
 		return err
 	}
+	defer hijacked.Close()
 
-	errchan := make(chan error)
-	donechan := make(chan struct{})
-	go func(
-		cancel context.CancelFunc,
-		hijacked types.HijackedResponse,
-		stdout *os.File,
-	) {
-		_, err = io.Copy(stdout, hijacked.Reader)
-		if err != nil {
-			cancel()
-			errchan <- err
-		}
-
-		donechan <- struct{}{}
-	}(cancel, hijacked, c.stdout.stream)
+	if stdin {
+		go func() {
+			io.Copy(hijacked.Conn, c.stdin.stream)
+			hijacked.CloseWrite()
+		}()
+	}
 
-	err = c.client.ContainerExecStart(
-		ctx,
-		resp.ID,
-		types.ExecStartCheck{},
-	)
+	if tty {
+		_, err = io.Copy(c.stdout.stream, hijacked.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(
+			c.stdout.stream,
+			c.stderr.stream,
+			hijacked.Reader,
+		)
+	}
 	if err != nil {
-		cancel()
 		return err
 	}
 
-	select {
-	case err := <-errchan:
+	code, err := c.execExitCode(ctx, resp.ID)
+	if err != nil {
 		return err
-	case <-donechan:
+	}
+
+	if code != 0 {
+		return &container.ExitError{Cmd: cmd, Code: code}
 	}
 
 	return nil
 }
+
+// execExitCode waits for the exec with the given ID to finish and returns its
+// exit code. The output stream can close slightly before the daemon marks the
+// exec as done, so it polls until that happens.
+func (c *Controller) execExitCode(ctx context.Context, id string) (int, error) {
+	for {
+		insp, err := c.client.ContainerExecInspect(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		if !insp.Running {
+			return insp.ExitCode, nil
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
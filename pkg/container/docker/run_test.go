@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestRun(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	d.execResult = fakeExec{Output: "hello\n"}
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	output := d.capture(&t, c)
+
+	err = c.Run(m, []string{"echo", "hello"}, container.RunOpts{})
+	if err != nil {
+		t.Fatal("Run()", nil, err)
+	}
+
+	if actual := output(); "hello\n" != actual {
+		t.Fatal("output", "hello\n", actual)
+	}
+
+	if "running" != d.containers[m.ID].Status {
+		t.Fatal("container status", "running", d.containers[m.ID].Status)
+	}
+
+	exec := d.execs["exec1"]
+	if exec == nil || len(exec.Cmd) != 2 || "hello" != exec.Cmd[1] {
+		t.Fatal("exec command", []string{"echo", "hello"}, exec)
+	}
+}
+
+func TestRunExitCode(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	d.execResult = fakeExec{ExitCode: 2}
+
+	c := d.controller(&t)
+
+	m, err := c.Create(testMetadata())
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	err = c.Run(m, []string{"make", "test"}, container.RunOpts{TTY: true})
+
+	exitErr, ok := err.(*container.ExitError)
+	if !ok {
+		t.Fatal("Run() error", &container.ExitError{Code: 2}, err)
+	}
+
+	if 2 != exitErr.Code {
+		t.Fatal("exit code", 2, exitErr.Code)
+	}
+
+	if !d.execs["exec1"].Tty {
+		t.Fatal("exec tty", true, false)
+	}
+}
+
+func TestRunMissingContainer(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	m := container.Metadata{ID: "nope"}
+
+	err := d.controller(&t).Run(m, []string{"true"}, container.RunOpts{})
+	if err == nil {
+		t.Fatal("Run() error", "no such container", err)
+	}
+
+	if len(d.execs) != 0 {
+		t.Fatal("execs", 0, len(d.execs))
+	}
+}
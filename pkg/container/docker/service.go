@@ -0,0 +1,309 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/api/types"
+	docker "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// serviceTimeout is how long services get to become healthy once they've been
+// started.
+var serviceTimeout = 2 * time.Minute
+
+// servicePollInterval is how often services are checked on while waiting for
+// them to become healthy.
+var servicePollInterval = 500 * time.Millisecond
+
+// createServices creates and starts every service on the container's network,
+// each once the services it depends on are healthy. It blocks until they're
+// all healthy. If anything goes wrong, the services that were already created
+// are removed.
+func (c *Controller) createServices(m container.Metadata) (container.Metadata, error) {
+	if len(m.Services) == 0 {
+		return m, nil
+	}
+
+	svcs := make([]container.Service, len(m.Services))
+	copy(svcs, m.Services)
+	m.Services = svcs
+
+	for i, svc := range m.Services {
+		if err := c.waitForDependencies(m.Services[:i], svc); err != nil {
+			c.removeServices(m)
+			return container.Metadata{}, err
+		}
+
+		// The service's container can be created, but fail to start, in
+		// which case it still needs to be removed.
+		id, err := c.createService(m, svc)
+		m.Services[i].ID = id
+		if err != nil {
+			c.removeServices(m)
+			return container.Metadata{}, err
+		}
+	}
+
+	if err := c.waitForServices(m.Services); err != nil {
+		c.removeServices(m)
+		return container.Metadata{}, err
+	}
+
+	return m, nil
+}
+
+func (c *Controller) createService(
+	m container.Metadata,
+	svc container.Service,
+) (string, error) {
+	if err := c.pullImage(svc.Image); err != nil {
+		return "", err
+	}
+
+	cpmap, hpmap := getPortMappings(svc.Ports)
+
+	ccfg := &docker.Config{
+		Image:        svc.Image,
+		Env:          svc.Envs,
+		ExposedPorts: cpmap,
+	}
+
+	if hc := svc.Healthcheck; hc != nil {
+		ccfg.Healthcheck = &docker.HealthConfig{
+			Test:     hc.Test,
+			Interval: hc.Interval,
+			Timeout:  hc.Timeout,
+			Retries:  hc.Retries,
+		}
+	}
+
+	hcfg := &docker.HostConfig{
+		PortBindings: hpmap,
+		Mounts:       getMounts(svc.Mounts),
+		NetworkMode:  docker.NetworkMode(m.Network),
+	}
+
+	// The alias is what makes the service reachable by its name from the
+	// environment's container.
+	ncfg := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			m.Network: {Aliases: []string{svc.Name}},
+		},
+	}
+
+	cnt, err := c.client.ContainerCreate(
+		context.Background(),
+		ccfg,
+		hcfg,
+		ncfg,
+		fmt.Sprintf("%v-%v", m.BaseName, svc.Name),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	err = c.client.ContainerStart(
+		context.Background(),
+		cnt.ID,
+		types.ContainerStartOptions{},
+	)
+	return cnt.ID, err
+}
+
+// pullImage pulls the image with the given name, unless it's already there.
+func (c *Controller) pullImage(name string) error {
+	_, _, err := c.client.ImageInspectWithRaw(context.Background(), name)
+	if err == nil {
+		return nil
+	}
+
+	if !client.IsErrImageNotFound(err) {
+		return err
+	}
+
+	resp, err := c.client.ImagePull(
+		context.Background(),
+		name,
+		types.ImagePullOptions{},
+	)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Close()
+
+	return c.showProgress(resp)
+}
+
+// startServices starts every service, each once the services it depends on are
+// healthy, and blocks until they're all healthy.
+func (c *Controller) startServices(m container.Metadata) error {
+	for i, svc := range m.Services {
+		if err := c.waitForDependencies(m.Services[:i], svc); err != nil {
+			return err
+		}
+
+		err := c.client.ContainerStart(
+			context.Background(),
+			svc.ID,
+			types.ContainerStartOptions{},
+		)
+		if err != nil {
+			return fmt.Errorf("error starting service %v: %v", svc.Name, err)
+		}
+	}
+
+	return c.waitForServices(m.Services)
+}
+
+// stopServices stops every service, in the reverse of the order they were
+// started in so that no service loses one it depends on while it's running.
+// Services that are already gone are skipped.
+func (c *Controller) stopServices(m container.Metadata) error {
+	for i := len(m.Services) - 1; i >= 0; i-- {
+		svc := m.Services[i]
+
+		exists, err := c.serviceExists(svc)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		timeout := stopTimeout
+		err = c.client.ContainerStop(context.Background(), svc.ID, &timeout)
+		if err != nil {
+			return fmt.Errorf("error stopping service %v: %v", svc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// removeServices removes every service that was created. Services that are
+// already gone are skipped.
+func (c *Controller) removeServices(m container.Metadata) error {
+	for _, svc := range m.Services {
+		exists, err := c.serviceExists(svc)
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		err = c.client.ContainerRemove(
+			context.Background(),
+			svc.ID,
+			types.ContainerRemoveOptions{
+				RemoveVolumes: true,
+				Force:         true,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error removing service %v: %v", svc.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// serviceExists reports whether the service's container is still there. Only
+// inspecting a container tells a missing one apart from other errors.
+func (c *Controller) serviceExists(svc container.Service) (bool, error) {
+	if svc.ID == "" {
+		return false, nil
+	}
+
+	_, err := c.client.ContainerInspect(context.Background(), svc.ID)
+	if client.IsErrContainerNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// waitForServices blocks until every service is healthy, or running if it
+// doesn't have a healthcheck. It gives up once a service exits or turns
+// unhealthy, or after serviceTimeout.
+func (c *Controller) waitForServices(svcs []container.Service) error {
+	deadline := time.Now().Add(serviceTimeout)
+
+	for _, svc := range svcs {
+		for {
+			ready, err := c.serviceReady(svc)
+			if err != nil {
+				return err
+			}
+
+			if ready {
+				break
+			}
+
+			if time.Now().After(deadline) {
+				return fmt.Errorf("timed out waiting for service %v to be healthy",
+					svc.Name)
+			}
+
+			time.Sleep(servicePollInterval)
+		}
+	}
+
+	return nil
+}
+
+// waitForDependencies blocks until the services svc depends on are healthy.
+// They're looked up in started, the services started before svc.
+func (c *Controller) waitForDependencies(
+	started []container.Service,
+	svc container.Service,
+) error {
+	deps := []container.Service{}
+	for _, name := range svc.DependsOn {
+		for _, s := range started {
+			if s.Name == name {
+				deps = append(deps, s)
+			}
+		}
+	}
+
+	return c.waitForServices(deps)
+}
+
+func (c *Controller) serviceReady(svc container.Service) (bool, error) {
+	cnt, err := c.client.ContainerInspect(context.Background(), svc.ID)
+	if err != nil {
+		return false, err
+	}
+
+	state := cnt.ContainerJSONBase.State
+	switch {
+	case !state.Running:
+		return false, fmt.Errorf("service %v exited with status %v", svc.Name,
+			state.ExitCode)
+	case state.Health == nil || state.Health.Status == types.Healthy:
+		return true, nil
+	case state.Health.Status == types.Unhealthy:
+		msg := fmt.Sprintf("service %v is unhealthy", svc.Name)
+		if n := len(state.Health.Log); n > 0 {
+			out := strings.TrimSpace(state.Health.Log[n-1].Output)
+			if out != "" {
+				msg += ": " + out
+			}
+		}
+
+		return false, errors.New(msg)
+	}
+
+	return false, nil
+}
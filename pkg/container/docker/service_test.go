@@ -0,0 +1,256 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func testServices() []container.Service {
+	return []container.Service{
+		{
+			Name:  "db",
+			Image: "postgres:11",
+			Envs:  []string{"POSTGRES_PASSWORD=secret"},
+			Healthcheck: &container.Healthcheck{
+				Test:     []string{"CMD", "pg_isready"},
+				Interval: time.Second,
+				Retries:  3,
+			},
+		},
+		{
+			Name:  "cache",
+			Image: "redis:5",
+			Ports: []container.PortBinding{
+				{ContainerPort: 6379, Protocol: "tcp"},
+			},
+		},
+	}
+}
+
+func TestCreateWithServices(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	d.images["redis:5"] = true
+
+	meta := testMetadata()
+	meta.Services = testServices()
+
+	m, err := d.controller(&t).Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if "envctl_foo" != m.Network {
+		t.Fatal("network", "envctl_foo", m.Network)
+	}
+
+	if _, ok := d.networks[m.Network]; !ok {
+		t.Fatal("created network", m.Network, d.networks)
+	}
+
+	if len(d.pulled) != 1 || "postgres:11" != d.pulled[0] {
+		t.Fatal("pulled images", []string{"postgres:11"}, d.pulled)
+	}
+
+	if meta.Services[0].ID != "" {
+		t.Fatal("passed in services", "untouched", meta.Services)
+	}
+
+	db, ok := d.containers[m.Services[0].ID]
+	if !ok {
+		t.Fatal("db service container", m.Services[0].ID, d.containers)
+	}
+
+	if "envctl_foo-db" != db.Name {
+		t.Fatal("db service name", "envctl_foo-db", db.Name)
+	}
+
+	if "running" != db.Status {
+		t.Fatal("db service status", "running", db.Status)
+	}
+
+	aliases := db.NetworkingConfig.EndpointsConfig["envctl_foo"].Aliases
+	if len(aliases) != 1 || "db" != aliases[0] {
+		t.Fatal("db service aliases", []string{"db"}, aliases)
+	}
+
+	hc := db.Config.Healthcheck
+	if hc == nil || strings.Join(hc.Test, " ") != "CMD pg_isready" ||
+		hc.Interval != int64(time.Second) || hc.Retries != 3 {
+
+		t.Fatal("db service healthcheck", "CMD pg_isready every 1s", hc)
+	}
+
+	cache := d.containers[m.Services[1].ID]
+	bindings := cache.HostConfig.PortBindings["6379/tcp"]
+	if len(bindings) != 1 || "" != bindings[0].HostPort {
+		t.Fatal("cache service ports", "6379/tcp on an auto port",
+			cache.HostConfig.PortBindings)
+	}
+
+	cnt := d.containers[m.ID]
+	if "envctl_foo" != cnt.HostConfig.NetworkMode {
+		t.Fatal("container network", "envctl_foo", cnt.HostConfig.NetworkMode)
+	}
+}
+
+func TestCreateUnhealthyService(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	d.health["envctl_foo-db"] = "unhealthy"
+
+	meta := testMetadata()
+	meta.Services = testServices()
+
+	_, err := d.controller(&t).Create(meta)
+	if err == nil || "service db is unhealthy: check output" != err.Error() {
+		t.Fatal("Create() error", "service db is unhealthy: check output", err)
+	}
+
+	for _, cnt := range d.containers {
+		t.Fatal("containers after Create()", nil, cnt.Name)
+	}
+
+	if len(d.networks) != 0 {
+		t.Fatal("networks after Create()", nil, d.networks)
+	}
+}
+
+func TestCreateServiceTimeout(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	defer func(timeout, interval time.Duration) {
+		serviceTimeout, servicePollInterval = timeout, interval
+	}(serviceTimeout, servicePollInterval)
+	serviceTimeout, servicePollInterval = 50*time.Millisecond, 10*time.Millisecond
+
+	d.health["envctl_foo-db"] = "starting"
+
+	meta := testMetadata()
+	meta.Services = testServices()
+
+	_, err := d.controller(&t).Create(meta)
+	expected := "timed out waiting for service db to be healthy"
+	if err == nil || expected != err.Error() {
+		t.Fatal("Create() error", expected, err)
+	}
+
+	if len(d.containers) != 0 || len(d.networks) != 0 {
+		t.Fatal("containers and networks after Create()", nil, d.containers)
+	}
+}
+
+func TestCreateServiceDependencies(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	defer func(timeout, interval time.Duration) {
+		serviceTimeout, servicePollInterval = timeout, interval
+	}(serviceTimeout, servicePollInterval)
+	serviceTimeout, servicePollInterval = 50*time.Millisecond, 10*time.Millisecond
+
+	d.health["envctl_foo-db"] = "starting"
+
+	meta := testMetadata()
+	meta.Services = testServices()
+	meta.Services[0].Mounts = []container.Mount{
+		{Type: container.MountVolume, Source: "pgdata", Destination: "/var/lib/postgresql/data"},
+	}
+	meta.Services[1].DependsOn = []string{"db"}
+
+	_, err := d.controller(&t).Create(meta)
+	expected := "timed out waiting for service db to be healthy"
+	if err == nil || expected != err.Error() {
+		t.Fatal("Create() error", expected, err)
+	}
+
+	// The cache's image is only pulled when it's created, which it shouldn't
+	// be until the db is healthy.
+	if len(d.pulled) != 1 || "postgres:11" != d.pulled[0] {
+		t.Fatal("pulled images", []string{"postgres:11"}, d.pulled)
+	}
+
+	delete(d.health, "envctl_foo-db")
+
+	m, err := d.controller(&t).Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	mounts := d.containers[m.Services[0].ID].HostConfig.Mounts
+	if len(mounts) != 1 || "pgdata" != mounts[0].Source ||
+		"/var/lib/postgresql/data" != mounts[0].Target {
+
+		t.Fatal("db service mounts", meta.Services[0].Mounts, mounts)
+	}
+}
+
+func TestServicesLifecycle(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	meta := testMetadata()
+	meta.Services = testServices()
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.Stop(m); err != nil {
+		t.Fatal("Stop()", nil, err)
+	}
+
+	for _, svc := range m.Services {
+		if "exited" != d.containers[svc.ID].Status {
+			t.Fatal("service status after Stop()", "exited",
+				d.containers[svc.ID].Status)
+		}
+	}
+
+	if err := c.Start(m); err != nil {
+		t.Fatal("Start()", nil, err)
+	}
+
+	for _, svc := range m.Services {
+		if "running" != d.containers[svc.ID].Status {
+			t.Fatal("service status after Start()", "running",
+				d.containers[svc.ID].Status)
+		}
+	}
+
+	// A service that was removed behind envctl's back doesn't keep the rest
+	// from being cleaned up.
+	delete(d.containers, m.Services[1].ID)
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if len(d.containers) != 0 {
+		t.Fatal("containers after Remove()", nil, d.containers)
+	}
+
+	if len(d.networks) != 0 {
+		t.Fatal("networks after Remove()", nil, d.networks)
+	}
+}
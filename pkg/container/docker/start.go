@@ -0,0 +1,23 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/api/types"
+)
+
+// Start starts the container with the given metadata back up, with its
+// filesystem as it was when it was stopped. Its services are started first,
+// and it's only started once they're healthy.
+func (c *Controller) Start(m container.Metadata) error {
+	if err := c.startServices(m); err != nil {
+		return err
+	}
+
+	return c.client.ContainerStart(
+		context.Background(),
+		m.ID,
+		types.ContainerStartOptions{},
+	)
+}
@@ -0,0 +1,24 @@
+package docker
+
+import (
+	"context"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// stopTimeout is how long the container gets to shut down gracefully before
+// it's killed.
+const stopTimeout = 10 * time.Second
+
+// Stop stops the container with the given metadata without removing it, so
+// that it can be started again later. Its services are stopped along with it.
+func (c *Controller) Stop(m container.Metadata) error {
+	timeout := stopTimeout
+	err := c.client.ContainerStop(context.Background(), m.ID, &timeout)
+	if err != nil {
+		return err
+	}
+
+	return c.stopServices(m)
+}
@@ -0,0 +1,52 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/api/types/filters"
+	volumetypes "github.com/docker/docker/api/types/volume"
+)
+
+// CreateVolume creates a named volume. Docker hands back the existing volume
+// when there's already one with the same name.
+func (c *Controller) CreateVolume(v container.Volume) error {
+	_, err := c.client.VolumeCreate(
+		context.Background(),
+		volumetypes.VolumesCreateBody{
+			Name:   v.Name,
+			Labels: v.Labels,
+		},
+	)
+
+	return err
+}
+
+// Volumes returns the volumes that have all the given labels.
+func (c *Controller) Volumes(labels map[string]string) ([]container.Volume, error) {
+	args := filters.NewArgs()
+	for k, v := range labels {
+		args.Add("label", fmt.Sprintf("%v=%v", k, v))
+	}
+
+	resp, err := c.client.VolumeList(context.Background(), args)
+	if err != nil {
+		return nil, err
+	}
+
+	vols := []container.Volume{}
+	for _, v := range resp.Volumes {
+		vols = append(vols, container.Volume{
+			Name:   v.Name,
+			Labels: v.Labels,
+		})
+	}
+
+	return vols, nil
+}
+
+// RemoveVolume removes the volume with the given name.
+func (c *Controller) RemoveVolume(name string) error {
+	return c.client.VolumeRemove(context.Background(), name, false)
+}
@@ -0,0 +1,84 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestVolumes(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	vols := []container.Volume{
+		{Name: "envctl-foo-npm", Labels: map[string]string{"project": "/src/foo"}},
+		{Name: "envctl-bar-npm", Labels: map[string]string{"project": "/src/bar"}},
+	}
+
+	for _, v := range vols {
+		if err := c.CreateVolume(v); err != nil {
+			t.Fatal("CreateVolume()", nil, err)
+		}
+	}
+
+	// Creating a volume that already exists hands back the existing one.
+	if err := c.CreateVolume(vols[0]); err != nil {
+		t.Fatal("creating an existing volume", nil, err)
+	}
+
+	actual, err := c.Volumes(map[string]string{"project": "/src/foo"})
+	if err != nil {
+		t.Fatal("Volumes()", nil, err)
+	}
+
+	if 1 != len(actual) || vols[0].Name != actual[0].Name {
+		t.Fatal("volumes", vols[:1], actual)
+	}
+
+	if "/src/foo" != actual[0].Labels["project"] {
+		t.Fatal("labels", vols[0].Labels, actual[0].Labels)
+	}
+
+	if err := c.RemoveVolume(vols[0].Name); err != nil {
+		t.Fatal("RemoveVolume()", nil, err)
+	}
+
+	if _, ok := d.volumes[vols[0].Name]; ok {
+		t.Fatal("volume after RemoveVolume()", nil, d.volumes[vols[0].Name])
+	}
+
+	if err := c.RemoveVolume("nope"); err == nil {
+		t.Fatal("removing a missing volume", "error", nil)
+	}
+}
+
+func TestRemoveVolumeInUse(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	d := newFakeDaemon(&t)
+	defer d.Close()
+
+	c := d.controller(&t)
+
+	if err := c.CreateVolume(container.Volume{Name: "cache"}); err != nil {
+		t.Fatal("CreateVolume()", nil, err)
+	}
+
+	m := testMetadata()
+	m.Mounts = []container.Mount{
+		{Type: container.MountVolume, Source: "cache", Destination: "/cache"},
+	}
+
+	if _, err := c.Create(m); err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.RemoveVolume("cache"); err == nil {
+		t.Fatal("removing a volume in use", "error", nil)
+	}
+}
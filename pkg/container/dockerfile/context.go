@@ -0,0 +1,200 @@
+package dockerfile
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/google/uuid"
+)
+
+// DirContext tars up the directory at dir to be sent to the builder as a build
+// context, leaving out anything matched by its
+// .dockerignore. Just like with "docker build", the Dockerfile and the
+// .dockerignore are always sent. The Dockerfile's contents are replaced by
+// the ones passed in.
+//
+// The Dockerfile's path is relative to dir. One that's outside of dir is sent
+// under a generated name, like "docker build -f" does, so the name to give the
+// builder is returned along with the context.
+func DirContext(
+	dir string,
+	dockerfile string,
+	contents []byte,
+) (*bytes.Buffer, string, error) {
+	excludes, err := readDockerignore(dir)
+	if err != nil {
+		return &bytes.Buffer{}, "", err
+	}
+
+	dockerfile = filepath.Clean(dockerfile)
+
+	name := filepath.ToSlash(dockerfile)
+	if dockerfile == ".." || strings.HasPrefix(dockerfile, ".."+string(filepath.Separator)) {
+		name = ".dockerfile." + uuid.New().String()
+	}
+
+	buf := &bytes.Buffer{}
+	wr := tar.NewWriter(buf)
+
+	err = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		// The Dockerfile is added once everything else is, since it might be
+		// somewhere that's skipped.
+		if rel == "." || rel == dockerfile {
+			return nil
+		}
+
+		if rel != ".dockerignore" {
+			skip, err := fileutils.Matches(rel, excludes)
+			if err != nil {
+				return err
+			}
+
+			// Directories can't be skipped outright if there are exceptions,
+			// since something inside of them might be let back in.
+			if skip && fi.IsDir() && !hasExceptions(excludes) {
+				return filepath.SkipDir
+			}
+
+			if skip {
+				return nil
+			}
+		}
+
+		link := ""
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+
+		hdr.Name = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+
+		if err := wr.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(wr, f)
+		return err
+	})
+	if err != nil {
+		return &bytes.Buffer{}, "", err
+	}
+
+	hdr := &tar.Header{
+		Name: name,
+		Mode: 0600,
+		Size: int64(len(contents)),
+	}
+
+	if err := wr.WriteHeader(hdr); err != nil {
+		return &bytes.Buffer{}, "", err
+	}
+
+	if _, err := wr.Write(contents); err != nil {
+		return &bytes.Buffer{}, "", err
+	}
+
+	if err := wr.Close(); err != nil {
+		return &bytes.Buffer{}, "", err
+	}
+
+	return buf, name, nil
+}
+
+func readDockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+
+	if err != nil {
+		return []string{}, err
+	}
+	defer f.Close()
+
+	return dockerignore.ReadAll(f)
+}
+
+func hasExceptions(patterns []string) bool {
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Truncate cuts off everything in a multi-stage Dockerfile after the stage
+// named target. Since stages can only depend on the ones before them, building
+// the result is the same as building the target stage.
+func Truncate(raw []byte, target string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	found := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+
+		if len(fields) > 0 && strings.EqualFold(fields[0], "FROM") {
+			if found {
+				return out.Bytes(), nil
+			}
+
+			n := len(fields)
+			if n >= 4 && strings.EqualFold(fields[n-2], "AS") &&
+				strings.EqualFold(fields[n-1], target) {
+
+				found = true
+			}
+		}
+
+		fmt.Fprintln(out, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("build target %q not found in Dockerfile", target)
+	}
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,242 @@
+package dockerfile
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestDirContext(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-build-context")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"Dockerfile.dev":       "FROM scratch\n",
+		".dockerignore":        "node_modules\n*.log\n!keep.log\nDockerfile.dev\n",
+		"main.go":              "package main\n",
+		"debug.log":            "ignored\n",
+		"keep.log":             "kept\n",
+		"node_modules/foo.js":  "ignored\n",
+		"pkg/sub/lib.go":       "package sub\n",
+		"pkg/sub/lib_test.log": "kept\n",
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal("creating test dir", nil, err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal("creating test file", nil, err)
+		}
+	}
+
+	dockerfile := "FROM alpine\n"
+
+	bldctx, name, err := DirContext(dir, "Dockerfile.dev", []byte(dockerfile))
+	if err != nil {
+		t.Fatal("DirContext()", "no errors", err)
+	}
+
+	if "Dockerfile.dev" != name {
+		t.Fatal("Dockerfile name", "Dockerfile.dev", name)
+	}
+
+	actual := []string{}
+	tarrd := tar.NewReader(bldctx)
+	for {
+		h, err := tarrd.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal("reading build context", nil, err)
+		}
+
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		// The Dockerfile is sent even though it's ignored, and with the
+		// contents that were passed in rather than the ones on disk.
+		if h.Name == "Dockerfile.dev" {
+			raw, _ := ioutil.ReadAll(tarrd)
+			if string(raw) != dockerfile {
+				t.Fatal("Dockerfile contents", dockerfile, string(raw))
+			}
+		}
+
+		actual = append(actual, h.Name)
+	}
+
+	sort.Strings(actual)
+
+	// Just like with "docker build", "*.log" only matches at the top level.
+	expected := []string{
+		".dockerignore",
+		"Dockerfile.dev",
+		"keep.log",
+		"main.go",
+		"pkg/sub/lib.go",
+		"pkg/sub/lib_test.log",
+	}
+
+	if strings.Join(expected, ",") != strings.Join(actual, ",") {
+		t.Fatal("build context files", expected, actual)
+	}
+}
+
+func TestDirContextDockerfile(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-build-context")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctxDir := filepath.Join(dir, "app")
+
+	files := map[string]string{
+		"Dockerfile":           "FROM scratch\n",
+		"app/.dockerignore":    "build\n",
+		"app/main.go":          "package main\n",
+		"app/build/Dockerfile": "FROM scratch\n",
+		"app/build/output.bin": "ignored\n",
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal("creating test dir", nil, err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal("creating test file", nil, err)
+		}
+	}
+
+	dockerfile := "FROM alpine\n"
+
+	// listFiles returns the names of the files in a build context, along with
+	// the contents of the one named df.
+	listFiles := func(bldctx io.Reader, df string) ([]string, string) {
+		names := []string{}
+		contents := ""
+
+		tarrd := tar.NewReader(bldctx)
+		for {
+			h, err := tarrd.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				t.Fatal("reading build context", nil, err)
+			}
+
+			if h.Typeflag == tar.TypeDir {
+				continue
+			}
+
+			if h.Name == df {
+				raw, _ := ioutil.ReadAll(tarrd)
+				contents = string(raw)
+			}
+
+			names = append(names, h.Name)
+		}
+
+		sort.Strings(names)
+
+		return names, contents
+	}
+
+	// The Dockerfile is sent even though the directory it's in is ignored.
+	bldctx, name, err := DirContext(ctxDir, "build/Dockerfile", []byte(dockerfile))
+	if err != nil {
+		t.Fatal("DirContext()", "no errors", err)
+	}
+
+	names, contents := listFiles(bldctx, name)
+
+	expected := []string{".dockerignore", "build/Dockerfile", "main.go"}
+	if "build/Dockerfile" != name || strings.Join(expected, ",") != strings.Join(names, ",") {
+		t.Fatal("build context files", expected, names)
+	}
+
+	if dockerfile != contents {
+		t.Fatal("Dockerfile contents", dockerfile, contents)
+	}
+
+	// A Dockerfile outside of the context is sent under another name.
+	bldctx, name, err = DirContext(ctxDir, "../Dockerfile", []byte(dockerfile))
+	if err != nil {
+		t.Fatal("DirContext()", "no errors", err)
+	}
+
+	if !strings.HasPrefix(name, ".dockerfile.") {
+		t.Fatal("Dockerfile name", ".dockerfile.<id>", name)
+	}
+
+	names, contents = listFiles(bldctx, name)
+
+	expected = []string{name, ".dockerignore", "main.go"}
+	if strings.Join(expected, ",") != strings.Join(names, ",") {
+		t.Fatal("build context files", expected, names)
+	}
+
+	if dockerfile != contents {
+		t.Fatal("Dockerfile contents", dockerfile, contents)
+	}
+}
+
+func TestTruncate(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	raw := `FROM golang:1.11 AS build
+RUN go build
+
+from alpine as dev
+COPY --from=build /app /app
+
+FROM scratch AS release
+COPY --from=build /app /app
+`
+
+	actual, err := Truncate([]byte(raw), "dev")
+	if err != nil {
+		t.Fatal("Truncate()", "no errors", err)
+	}
+
+	expected := `FROM golang:1.11 AS build
+RUN go build
+
+from alpine as dev
+COPY --from=build /app /app
+
+`
+
+	if expected != string(actual) {
+		t.Fatal("truncated Dockerfile", expected, string(actual))
+	}
+
+	_, err = Truncate([]byte(raw), "missing")
+	if err == nil {
+		t.Fatal("truncating to a missing target", "an error", err)
+	}
+}
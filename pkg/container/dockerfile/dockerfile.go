@@ -0,0 +1,109 @@
+// Package dockerfile generates the Dockerfiles and build contexts used to build
+// environments' images. They're shared by every container runtime that builds
+// images from Dockerfiles.
+package dockerfile
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/alecthomas/template"
+)
+
+// Baked in bootstrap steps run with the same variables, working directory and
+// user as the ones run in the environment after it's created. The variables
+// are set with ENV, so they're saved in the image, secrets and all.
+var dockerfileTpl = `FROM {{ .BaseImage }}{{ if .Bootstrap }}{{ range .Envs }}
+	ENV {{ envForm . }}{{ end }}
+	WORKDIR "{{ .Mount.Destination }}"{{ if .User }}
+	USER {{ .User }}{{ end }}{{ range .Bootstrap }}
+	RUN {{ shellForm $.Shell . }}{{ end }}{{ end }}
+	VOLUME ["{{ .Mount.Destination }}"]
+	WORKDIR "{{ .Mount.Destination }}"
+	ENTRYPOINT ["{{ .Shell }}"]`
+
+// Generate returns the Dockerfile for the image of the environment described
+// by m. The image is built on top of m's base image, and sets things up for
+// the repo to be mounted and the shell to be run.
+func Generate(m container.Metadata) (*bytes.Buffer, error) {
+	buf := &bytes.Buffer{}
+
+	funcs := template.FuncMap{"shellForm": shellForm, "envForm": envForm}
+
+	// The variables come in no particular order. Any change to it would
+	// change the ENV lines, and keep the bootstrap steps after them from
+	// being cached.
+	m.Envs = append([]string{}, m.Envs...)
+	sort.Strings(m.Envs)
+
+	tpl, err := template.New("Dockerfile").Funcs(funcs).Parse(dockerfileTpl)
+	if err != nil {
+		return &bytes.Buffer{}, err
+	}
+
+	err = tpl.Execute(buf, &m)
+	if err != nil {
+		return &bytes.Buffer{}, err
+	}
+
+	return buf, nil
+}
+
+// shellForm returns the JSON array form of a Dockerfile instruction that runs
+// cmd with the given shell, so that it doesn't depend on the image having
+// /bin/sh.
+func shellForm(shell string, cmd string) (string, error) {
+	buf := &bytes.Buffer{}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode([]string{shell, "-c", cmd}); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// envForm returns a KEY=value variable as the argument of an ENV instruction,
+// with the value quoted so that it's used as is instead of being expanded.
+func envForm(env string) string {
+	parts := strings.SplitN(env, "=", 2)
+	if len(parts) == 1 {
+		return parts[0] + `=""`
+	}
+
+	val := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`).Replace(parts[1])
+
+	return parts[0] + `="` + val + `"`
+}
+
+// Context returns a build context containing nothing but the given Dockerfile.
+func Context(raw *bytes.Buffer) (*bytes.Buffer, error) {
+	buf := bytes.NewBuffer([]byte{})
+	wr := tar.NewWriter(buf)
+
+	hdr := &tar.Header{
+		Name: "Dockerfile",
+		Mode: 0600,
+		Size: int64(raw.Len()),
+	}
+
+	if err := wr.WriteHeader(hdr); err != nil {
+		return &bytes.Buffer{}, err
+	}
+
+	if _, err := wr.Write(raw.Bytes()); err != nil {
+		return &bytes.Buffer{}, err
+	}
+
+	padlen := 512 - (buf.Len() % 512)
+	padding := make([]byte, padlen)
+
+	padded := bytes.NewBuffer(append(buf.Bytes(), padding...))
+
+	return padded, nil
+}
@@ -0,0 +1,120 @@
+package dockerfile
+
+import (
+	"archive/tar"
+	"io"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestGenerate(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	testm := container.Metadata{
+		BaseImage: "scratch",
+		Mount: container.Mount{
+			Source:      "",
+			Destination: "/test-path",
+		},
+		Shell: "/testsh",
+	}
+
+	buf, err := Generate(testm)
+	if err != nil {
+		t.Fatal("errors", nil, err)
+	}
+
+	expected := `FROM scratch
+	VOLUME ["/test-path"]
+	WORKDIR "/test-path"
+	ENTRYPOINT ["/testsh"]`
+
+	actual := buf.String()
+	if expected != actual {
+		t.Fatal("Dockerfile build", expected, actual)
+	}
+}
+
+func TestGenerateWithBootstrap(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	testm := container.Metadata{
+		BaseImage: "scratch",
+		Mount: container.Mount{
+			Source:      "",
+			Destination: "/test-path",
+		},
+		Shell: "/testsh",
+		Envs:  []string{`TOKEN=a"b$c`, "NODE_ENV=development"},
+		User:  "app",
+		Bootstrap: []string{
+			"apt-get update && apt-get install -y make",
+			`echo "done" > /envctl`,
+		},
+	}
+
+	buf, err := Generate(testm)
+	if err != nil {
+		t.Fatal("errors", nil, err)
+	}
+
+	expected := `FROM scratch
+	ENV NODE_ENV="development"
+	ENV TOKEN="a\"b\$c"
+	WORKDIR "/test-path"
+	USER app
+	RUN ["/testsh","-c","apt-get update && apt-get install -y make"]
+	RUN ["/testsh","-c","echo \"done\" > /envctl"]
+	VOLUME ["/test-path"]
+	WORKDIR "/test-path"
+	ENTRYPOINT ["/testsh"]`
+
+	actual := buf.String()
+	if expected != actual {
+		t.Fatal("Dockerfile build", expected, actual)
+	}
+}
+
+func TestContext(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	testm := container.Metadata{
+		BaseImage: "scratch",
+		Mount: container.Mount{
+			Source:      "",
+			Destination: "/test-path",
+		},
+		Shell: "/testsh",
+	}
+
+	buf, err := Generate(testm)
+	if err != nil {
+		t.Fatal("Dockerfile build", "no errors", err)
+	}
+
+	bldctx, err := Context(buf)
+	if err != nil {
+		t.Fatal("Context()", "no errors", err)
+	}
+
+	tarrd := tar.NewReader(bldctx)
+	h, err := tarrd.Next()
+	for err != io.EOF {
+		// The only thing in here should be the Dockerfile. If this is the case,
+		// the for loop will exit before hitting this check again. If it's not,
+		// it will fail because whatever is next won't match this.
+		if h.FileInfo().Name() != "Dockerfile" {
+			t.Fatal("tar header", "Dockerfile", h.FileInfo().Name())
+		}
+
+		expected := int64(buf.Len())
+		actual := h.FileInfo().Size()
+		if expected != actual {
+			t.Fatal("tar file size", expected, actual)
+		}
+
+		h, err = tarrd.Next()
+	}
+}
@@ -0,0 +1,479 @@
+// Package fake is an in-memory container.Controller. It simulates containers,
+// images and the commands run in them closely enough for code built on
+// envctl to be tested without a container runtime.
+package fake
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+func init() {
+	container.Register("fake", func(container.Options) (container.Controller, error) {
+		return NewController(), nil
+	})
+}
+
+// Result is what a command run in a container does.
+type Result struct {
+	// Output is written to the controller's Stdout.
+	Output   string
+	ExitCode int
+}
+
+// Exec is a record of a command that was run in a container.
+type Exec struct {
+	ContainerID string
+	Cmd         []string
+	Opts        container.RunOpts
+}
+
+// Container is a simulated container.
+type Container struct {
+	Metadata container.Metadata
+	State    container.State
+	// Attached is how many times the container has been attached to.
+	Attached int
+}
+
+// Controller is an in-memory implementation of container.Controller. It's safe
+// for concurrent use.
+type Controller struct {
+	// Stdout is where the output of commands is written. It defaults to
+	// ioutil.Discard.
+	Stdout io.Writer
+
+	mu         sync.Mutex
+	next       int
+	nextPort   int
+	containers map[string]*Container
+	images     map[string]bool
+	volumes    map[string]container.Volume
+	networks   map[string]bool
+	scripts    map[string]Result
+	execs      []Exec
+}
+
+// NewController returns a Controller without any containers or images.
+func NewController() *Controller {
+	return &Controller{
+		Stdout:     ioutil.Discard,
+		nextPort:   32768,
+		containers: map[string]*Container{},
+		images:     map[string]bool{},
+		volumes:    map[string]container.Volume{},
+		networks:   map[string]bool{},
+		scripts:    map[string]Result{},
+	}
+}
+
+// Script sets what running the given command does. The command is matched
+// against the arguments passed to Run joined by spaces. Commands that haven't
+// been scripted succeed without any output.
+func (c *Controller) Script(cmd string, r Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.scripts[cmd] = r
+}
+
+// Execs returns every command that's been run, in order.
+func (c *Controller) Execs() []Exec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Exec{}, c.execs...)
+}
+
+// Container returns the container with the given ID, if there is one.
+func (c *Controller) Container(id string) (Container, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt, ok := c.containers[id]
+	if !ok {
+		return Container{}, false
+	}
+
+	return *cnt, true
+}
+
+// Containers returns how many containers there are.
+func (c *Controller) Containers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.containers)
+}
+
+// HasImage reports whether an image with the given name exists.
+func (c *Controller) HasImage(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.images[name]
+}
+
+// AddNetwork simulates a network created outside of envctl, like one created
+// by docker-compose, for containers to join as an external network.
+func (c *Controller) AddNetwork(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.networks[name] = true
+}
+
+// HasNetwork reports whether a network with the given name exists.
+func (c *Controller) HasNetwork(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.networks[name]
+}
+
+// Exit simulates the main process of a running container exiting on its own
+// with the given exit code.
+func (c *Controller) Exit(id string, code int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt, err := c.container(id)
+	if err != nil {
+		return err
+	}
+
+	cnt.State = container.State{Status: container.StatusExited, ExitCode: code}
+	return nil
+}
+
+// Delete simulates a container being removed behind envctl's back, like with
+// "docker rm".
+func (c *Controller) Delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.containers, id)
+}
+
+// DeleteImage simulates an image being removed behind envctl's back, like
+// with "docker rmi".
+func (c *Controller) DeleteImage(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.images, name)
+}
+
+// Create builds an image for the container and creates it. Like a real
+// runtime, it fails if there's no base image to build on, or if there's
+// already a container with the same name, or an external network that
+// doesn't exist. The container gets a network of its own, and services get a
+// container of their own on it, which is running and healthy right away.
+func (c *Controller) Create(m container.Metadata) (container.Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if m.BaseImage == "" && m.Build == nil {
+		return container.Metadata{}, fmt.Errorf("no base image")
+	}
+
+	for _, cnt := range c.containers {
+		if m.BaseName != "" && cnt.Metadata.BaseName == m.BaseName {
+			return container.Metadata{}, fmt.Errorf(
+				"container name %q is already in use", m.BaseName)
+		}
+	}
+
+	for _, name := range m.ExternalNetworks {
+		if !c.networks[name] {
+			return container.Metadata{}, fmt.Errorf(
+				"external network %v doesn't exist", name)
+		}
+	}
+
+	c.next++
+
+	if m.Build != nil {
+		m.BaseImage = fmt.Sprintf("%v-base:fake-%v", m.BaseName, c.next)
+		c.images[m.BaseImage] = true
+	}
+
+	m.ID = fmt.Sprintf("fake-%v", c.next)
+	m.ImageID = fmt.Sprintf("%v:fake-%v", m.BaseName, c.next)
+	c.images[m.ImageID] = true
+
+	m.Network = m.BaseName
+	c.networks[m.Network] = true
+
+	if len(m.Services) > 0 {
+		svcs := make([]container.Service, len(m.Services))
+		for i, svc := range m.Services {
+			c.next++
+			svc.ID = fmt.Sprintf("fake-%v", c.next)
+			c.images[svc.Image] = true
+
+			c.containers[svc.ID] = &Container{
+				Metadata: container.Metadata{
+					ID:        svc.ID,
+					BaseName:  fmt.Sprintf("%v-%v", m.BaseName, svc.Name),
+					BaseImage: svc.Image,
+					ImageID:   svc.Image,
+					Envs:      svc.Envs,
+					Ports:     svc.Ports,
+					Network:   m.Network,
+				},
+			}
+
+			if err := c.start(svc.ID); err != nil {
+				return container.Metadata{}, err
+			}
+
+			svcs[i] = svc
+		}
+
+		m.Services = svcs
+	}
+
+	c.containers[m.ID] = &Container{
+		Metadata: m,
+		State:    container.State{Status: container.StatusCreated},
+	}
+
+	return m, nil
+}
+
+// Remove removes the container along with the images built for it, its
+// services and their network. A container that's already gone isn't an
+// error, and the rest is removed anyway.
+func (c *Controller) Remove(m container.Metadata) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.containers, m.ID)
+	delete(c.images, m.ImageID)
+
+	if m.Build != nil {
+		delete(c.images, m.BaseImage)
+	}
+
+	for _, svc := range m.Services {
+		delete(c.containers, svc.ID)
+	}
+
+	delete(c.networks, m.Network)
+	return nil
+}
+
+// Start starts the container, after its services. Starting a running
+// container isn't an error.
+func (c *Controller) Start(m container.Metadata) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, svc := range m.Services {
+		if err := c.start(svc.ID); err != nil {
+			return err
+		}
+	}
+
+	return c.start(m.ID)
+}
+
+// Stop stops the container and its services. Stopping a container that isn't
+// running isn't an error, and services that are gone are skipped.
+func (c *Controller) Stop(m container.Metadata) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt, err := c.container(m.ID)
+	if err != nil {
+		return err
+	}
+
+	stop(cnt)
+
+	for _, svc := range m.Services {
+		if cnt, ok := c.containers[svc.ID]; ok {
+			stop(cnt)
+		}
+	}
+
+	return nil
+}
+
+// Attach starts the container and counts the attachment. There's no shell to
+// interact with, so it returns right away.
+func (c *Controller) Attach(m container.Metadata) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.start(m.ID); err != nil {
+		return err
+	}
+
+	c.containers[m.ID].Attached++
+	return nil
+}
+
+// Run starts the container and runs the command in it, according to its
+// script. A non-zero exit code is returned as a *container.ExitError.
+func (c *Controller) Run(
+	m container.Metadata,
+	cmd []string,
+	opts container.RunOpts,
+) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.start(m.ID); err != nil {
+		return err
+	}
+
+	c.execs = append(c.execs, Exec{
+		ContainerID: m.ID,
+		Cmd:         append([]string{}, cmd...),
+		Opts:        opts,
+	})
+
+	r := c.scripts[strings.Join(cmd, " ")]
+	if _, err := io.WriteString(c.Stdout, r.Output); err != nil {
+		return err
+	}
+
+	if r.ExitCode != 0 {
+		return &container.ExitError{Cmd: cmd, Code: r.ExitCode}
+	}
+
+	return nil
+}
+
+// Inspect returns the state of the container. Containers and images that are
+// gone are reported the same way a real runtime reports them.
+func (c *Controller) Inspect(m container.Metadata) (container.State, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cnt, ok := c.containers[m.ID]
+	if !ok {
+		return container.State{Status: container.StatusMissing}, nil
+	}
+
+	if !c.images[cnt.Metadata.ImageID] {
+		return container.State{Status: container.StatusImageMissing}, nil
+	}
+
+	return cnt.State, nil
+}
+
+// CreateVolume creates a volume, unless there's already one with the same
+// name.
+func (c *Controller) CreateVolume(v container.Volume) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.volumes[v.Name]; !ok {
+		c.volumes[v.Name] = v
+	}
+
+	return nil
+}
+
+// Volumes returns the volumes that have all the given labels, sorted by name.
+func (c *Controller) Volumes(labels map[string]string) ([]container.Volume, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	vols := []container.Volume{}
+	for _, v := range c.volumes {
+		if hasLabels(v, labels) {
+			vols = append(vols, v)
+		}
+	}
+
+	sort.Slice(vols, func(i, j int) bool { return vols[i].Name < vols[j].Name })
+	return vols, nil
+}
+
+// RemoveVolume removes a volume. Like a real runtime, it fails if the volume
+// is mounted in a container.
+func (c *Controller) RemoveVolume(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.volumes[name]; !ok {
+		return fmt.Errorf("no such volume: %v", name)
+	}
+
+	for id, cnt := range c.containers {
+		for _, m := range cnt.Metadata.Mounts {
+			if m.Type == container.MountVolume && m.Source == name {
+				return fmt.Errorf("volume %v is in use by container %v", name, id)
+			}
+		}
+	}
+
+	delete(c.volumes, name)
+	return nil
+}
+
+// Ping always succeeds, since there's nothing to reach.
+func (c *Controller) Ping() error {
+	return nil
+}
+
+func hasLabels(v container.Volume, labels map[string]string) bool {
+	for k, val := range labels {
+		if v.Labels[k] != val {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *Controller) container(id string) (*Container, error) {
+	cnt, ok := c.containers[id]
+	if !ok {
+		return nil, fmt.Errorf("no such container: %v", id)
+	}
+
+	return cnt, nil
+}
+
+func stop(cnt *Container) {
+	if cnt.State.Status == container.StatusRunning {
+		cnt.State = container.State{Status: container.StatusExited}
+	}
+}
+
+func (c *Controller) start(id string) error {
+	cnt, err := c.container(id)
+	if err != nil {
+		return err
+	}
+
+	if cnt.State.Status == container.StatusRunning {
+		return nil
+	}
+
+	// Ports without a host port get the next one, like a real runtime
+	// picking a free one.
+	ports := []container.PortBinding{}
+	for _, p := range cnt.Metadata.Ports {
+		if p.HostPort == 0 {
+			c.nextPort++
+			p.HostPort = c.nextPort
+		}
+
+		ports = append(ports, p)
+	}
+
+	cnt.State = container.State{Status: container.StatusRunning, Ports: ports}
+	return nil
+}
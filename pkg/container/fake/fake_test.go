@@ -0,0 +1,321 @@
+package fake
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestLifecycle(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+
+	m, err := c.Create(container.Metadata{
+		BaseName:  "envctl_foo",
+		BaseImage: "alpine",
+	})
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	steps := []struct {
+		name     string
+		fn       func(container.Metadata) error
+		expected container.Status
+	}{
+		{"create", func(container.Metadata) error { return nil }, container.StatusCreated},
+		{"start", c.Start, container.StatusRunning},
+		{"stop", c.Stop, container.StatusExited},
+		{"attach", c.Attach, container.StatusRunning},
+		{"remove", c.Remove, container.StatusMissing},
+	}
+
+	for _, step := range steps {
+		if err := step.fn(m); err != nil {
+			t.Fatal(step.name, nil, err)
+		}
+
+		state, err := c.Inspect(m)
+		if err != nil {
+			t.Fatal("Inspect() after "+step.name, nil, err)
+		}
+
+		if step.expected != state.Status {
+			t.Fatal("status after "+step.name, step.expected, state.Status)
+		}
+	}
+
+	if c.HasImage(m.ImageID) {
+		t.Fatal("image after remove", false, true)
+	}
+
+	if err := c.Start(m); err == nil {
+		t.Fatal("Start() after remove", "error", err)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove() after remove", nil, err)
+	}
+}
+
+func TestCreateErrors(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+
+	if _, err := c.Create(container.Metadata{BaseName: "foo"}); err == nil {
+		t.Fatal("Create() without base image", "error", err)
+	}
+
+	m := container.Metadata{BaseName: "foo", BaseImage: "alpine"}
+	if _, err := c.Create(m); err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if _, err := c.Create(m); err == nil {
+		t.Fatal("Create() with name in use", "error", err)
+	}
+}
+
+func TestRun(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	out := &bytes.Buffer{}
+	c := NewController()
+	c.Stdout = out
+	c.Script("make test", Result{Output: "FAIL\n", ExitCode: 2})
+
+	m, _ := c.Create(container.Metadata{BaseImage: "alpine"})
+
+	if err := c.Run(m, []string{"make", "deps"}, container.RunOpts{}); err != nil {
+		t.Fatal("Run() unscripted", nil, err)
+	}
+
+	err := c.Run(m, []string{"make", "test"}, container.RunOpts{TTY: true})
+
+	exitErr, ok := err.(*container.ExitError)
+	if !ok || 2 != exitErr.Code {
+		t.Fatal("Run() error", &container.ExitError{Code: 2}, err)
+	}
+
+	if "FAIL\n" != out.String() {
+		t.Fatal("output", "FAIL\n", out.String())
+	}
+
+	execs := c.Execs()
+	if len(execs) != 2 || !execs[1].Opts.TTY || "test" != execs[1].Cmd[1] {
+		t.Fatal("execs", "make deps, make test", execs)
+	}
+}
+
+func TestOutsideChanges(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+	m, _ := c.Create(container.Metadata{BaseImage: "alpine"})
+	c.Start(m)
+
+	c.Exit(m.ID, 137)
+	state, _ := c.Inspect(m)
+	expected := container.State{Status: container.StatusExited, ExitCode: 137}
+	if expected.Status != state.Status || expected.ExitCode != state.ExitCode {
+		t.Fatal("state after exit", expected, state)
+	}
+
+	c.DeleteImage(m.ImageID)
+	state, _ = c.Inspect(m)
+	if container.StatusImageMissing != state.Status {
+		t.Fatal("status after image delete", container.StatusImageMissing,
+			state.Status)
+	}
+
+	c.Delete(m.ID)
+	state, _ = c.Inspect(m)
+	if container.StatusMissing != state.Status {
+		t.Fatal("status after delete", container.StatusMissing, state.Status)
+	}
+}
+
+func TestVolumes(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+
+	vols := []container.Volume{
+		{Name: "b", Labels: map[string]string{"project": "foo"}},
+		{Name: "a", Labels: map[string]string{"project": "foo"}},
+		{Name: "c", Labels: map[string]string{"project": "bar"}},
+	}
+
+	for _, v := range vols {
+		if err := c.CreateVolume(v); err != nil {
+			t.Fatal("CreateVolume()", nil, err)
+		}
+	}
+
+	// Creating it again doesn't replace it.
+	c.CreateVolume(container.Volume{Name: "a"})
+
+	actual, err := c.Volumes(map[string]string{"project": "foo"})
+	if err != nil {
+		t.Fatal("Volumes()", nil, err)
+	}
+
+	if 2 != len(actual) || "a" != actual[0].Name || "b" != actual[1].Name {
+		t.Fatal("volumes", vols[:2], actual)
+	}
+
+	if "foo" != actual[0].Labels["project"] {
+		t.Fatal("labels", "foo", actual[0].Labels["project"])
+	}
+
+	_, err = c.Create(container.Metadata{
+		BaseName:  "envctl_foo",
+		BaseImage: "alpine",
+		Mounts: []container.Mount{
+			{Type: container.MountVolume, Source: "a", Destination: "/cache"},
+		},
+	})
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.RemoveVolume("a"); err == nil {
+		t.Fatal("removing a volume in use", "error", nil)
+	}
+
+	if err := c.RemoveVolume("b"); err != nil {
+		t.Fatal("RemoveVolume()", nil, err)
+	}
+
+	actual, _ = c.Volumes(nil)
+	if 2 != len(actual) {
+		t.Fatal("volumes left", 2, len(actual))
+	}
+}
+
+func TestPorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+	m, _ := c.Create(container.Metadata{
+		BaseImage: "alpine",
+		Ports: []container.PortBinding{
+			{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{ContainerPort: 3000, Protocol: "tcp"},
+		},
+	})
+
+	state, _ := c.Inspect(m)
+	if 0 != len(state.Ports) {
+		t.Fatal("ports before starting", nil, state.Ports)
+	}
+
+	c.Start(m)
+	state, _ = c.Inspect(m)
+
+	expected := []container.PortBinding{
+		{HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{HostPort: 32769, ContainerPort: 3000, Protocol: "tcp"},
+	}
+
+	if len(expected) != len(state.Ports) ||
+		expected[0] != state.Ports[0] ||
+		expected[1] != state.Ports[1] {
+
+		t.Fatal("published ports", expected, state.Ports)
+	}
+}
+
+func TestServices(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+
+	m, err := c.Create(container.Metadata{
+		BaseName:  "foo",
+		BaseImage: "alpine",
+		Services: []container.Service{
+			{Name: "db", Image: "postgres:11"},
+			{Name: "cache", Image: "redis:5"},
+		},
+	})
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if "foo" != m.Network || !c.HasNetwork("foo") {
+		t.Fatal("network", "foo", m.Network)
+	}
+
+	if 3 != c.Containers() {
+		t.Fatal("containers", 3, c.Containers())
+	}
+
+	for _, svc := range m.Services {
+		cnt, ok := c.Container(svc.ID)
+		if !ok || container.StatusRunning != cnt.State.Status {
+			t.Fatal("service "+svc.Name, container.StatusRunning, cnt.State)
+		}
+	}
+
+	if err := c.Stop(m); err != nil {
+		t.Fatal("Stop()", nil, err)
+	}
+
+	db, _ := c.Container(m.Services[0].ID)
+	if container.StatusExited != db.State.Status {
+		t.Fatal("service after Stop()", container.StatusExited, db.State.Status)
+	}
+
+	if err := c.Start(m); err != nil {
+		t.Fatal("Start()", nil, err)
+	}
+
+	db, _ = c.Container(m.Services[0].ID)
+	if container.StatusRunning != db.State.Status {
+		t.Fatal("service after Start()", container.StatusRunning, db.State.Status)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if 0 != c.Containers() || c.HasNetwork("foo") {
+		t.Fatal("containers and network after Remove()", 0, c.Containers())
+	}
+}
+
+func TestExternalNetworks(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	c := NewController()
+
+	meta := container.Metadata{
+		BaseName:         "foo",
+		BaseImage:        "alpine",
+		ExternalNetworks: []string{"compose_default"},
+	}
+
+	if _, err := c.Create(meta); err == nil {
+		t.Fatal("Create() error", "external network doesn't exist", err)
+	}
+
+	c.AddNetwork("compose_default")
+
+	m, err := c.Create(meta)
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if err := c.Remove(m); err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+
+	if c.HasNetwork("foo") || !c.HasNetwork("compose_default") {
+		t.Fatal("networks after Remove()", "compose_default", "foo")
+	}
+}
@@ -0,0 +1,161 @@
+package podman
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	gosignal "os/signal"
+	"strconv"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/pkg/signal"
+	"github.com/docker/docker/pkg/term"
+)
+
+// Attach attaches the terminal session of the currently running
+// program to the container interactively.
+func (c *Controller) Attach(m container.Metadata) error {
+	restoreStdout, restoreStdin, err := c.makeRawTerminal()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"stream": {"true"},
+		"stdin":  {"true"},
+		"stdout": {"true"},
+		"stderr": {"true"},
+	}
+
+	conn, reader, err := c.hijack("/containers/"+m.ID+"/attach", query, nil)
+	if err != nil {
+		restoreStdin()
+		restoreStdout()
+		return err
+	}
+	defer conn.Close()
+
+	if err := c.Start(m); err != nil {
+		restoreStdin()
+		restoreStdout()
+		return err
+	}
+
+	c.mirrorContainerTTY(m.ID)
+
+	errchan := make(chan error)
+	donechan := make(chan struct{})
+
+	go func() {
+		_, err := io.Copy(c.stdout.stream, reader)
+		restoreStdout()
+		if err != nil {
+			errchan <- err
+			return
+		}
+
+		donechan <- struct{}{}
+	}()
+
+	go func() {
+		_, err := io.Copy(conn, c.stdin.stream)
+		restoreStdin()
+		if err != nil {
+			errchan <- err
+			return
+		}
+
+		closeWrite(conn)
+	}()
+
+	// Depending on the underlying image's entrypoint, there could be cases
+	// where there's no command prompt. This could trick the user into thinking
+	// that the process is hung, when in fact there just hasn't been anything
+	// to write to stdout.
+	fmt.Fprintf(
+		c.stdout.stream,
+		"If you don't see a command prompt, try pressing enter.\r\n",
+	)
+
+	select {
+	case err = <-errchan:
+		return err
+	case <-donechan:
+	}
+
+	return nil
+}
+
+// makeRawTerminal sets the terminal currently pointed to by stdin and stdout
+// into a raw terminal, and returns callbacks to restore each of them. Streams
+// that aren't terminals are left alone, and their callbacks do nothing.
+func (c *Controller) makeRawTerminal() (func() error, func() error, error) {
+	noop := func() error { return nil }
+
+	restoreStdout := noop
+	if term.IsTerminal(c.stdout.fd) {
+		oldStdout, err := term.MakeRaw(c.stdout.fd)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		restoreStdout = func() error {
+			return term.RestoreTerminal(c.stdout.fd, oldStdout)
+		}
+	}
+
+	restoreStdin := noop
+	if term.IsTerminal(c.stdin.fd) {
+		oldStdin, err := term.MakeRaw(c.stdin.fd)
+		if err != nil {
+			restoreStdout()
+			return nil, nil, err
+		}
+
+		restoreStdin = func() error {
+			return term.RestoreTerminal(c.stdin.fd, oldStdin)
+		}
+	}
+
+	return restoreStdout, restoreStdin, nil
+}
+
+func (ts *termStream) getTTYSize() (uint, uint) {
+	ws, err := term.GetWinsize(ts.fd)
+	if err != nil || ws == nil {
+		return 0, 0
+	}
+
+	return uint(ws.Width), uint(ws.Height)
+}
+
+// mirrorContainerTTY handles keeping the tty dimensions in sync from the host
+// to the container.
+func (c *Controller) mirrorContainerTTY(cntid string) {
+	handleTerminalResize := func() {
+		width, height := c.stdout.getTTYSize()
+		if width == 0 && height == 0 {
+			return
+		}
+
+		query := url.Values{
+			"w": {strconv.FormatUint(uint64(width), 10)},
+			"h": {strconv.FormatUint(uint64(height), 10)},
+		}
+
+		c.call("POST", "/containers/"+cntid+"/resize", query, nil, nil)
+	}
+
+	// Run this the first time to establish the link between the container's TTY
+	// and the terminal emulator's TTY.
+	handleTerminalResize()
+
+	sigchan := make(chan os.Signal, 1)
+	gosignal.Notify(sigchan, signal.SIGWINCH)
+	go func() {
+		for range sigchan {
+			handleTerminalResize()
+		}
+	}()
+}
@@ -0,0 +1,100 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/pkg/container/dockerfile"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/term"
+	"github.com/google/uuid"
+)
+
+// buildImage will build an image based on the passed in metadata. It returns
+// the name of the built image, as <m.BaseName:UUID>, or an error.
+func (c *Controller) buildImage(m container.Metadata) (string, error) {
+	df, err := dockerfile.Generate(m)
+	if err != nil {
+		return "", err
+	}
+
+	buildContext, err := dockerfile.Context(df)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%v:%v", m.BaseName, uuid.New().String())
+	query := url.Values{
+		"t":       {name},
+		"nocache": {strconv.FormatBool(m.NoCache)},
+	}
+
+	return name, c.build(buildContext, query)
+}
+
+// buildBaseImage builds the image described by the metadata's Build settings,
+// which the environment's own image is then built on top of. It returns the
+// name of the built image, as <m.BaseName-base:UUID>, or an error.
+func (c *Controller) buildBaseImage(m container.Metadata) (string, error) {
+	b := m.Build
+
+	df := b.Dockerfile
+	if df == "" {
+		df = "Dockerfile"
+	}
+
+	raw, err := ioutil.ReadFile(filepath.Join(b.Context, df))
+	if err != nil {
+		return "", err
+	}
+
+	buildContext, dfName, err := dockerfile.DirContext(b.Context, df, raw)
+	if err != nil {
+		return "", err
+	}
+
+	args, err := json.Marshal(b.Args)
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%v-base:%v", m.BaseName, uuid.New().String())
+	query := url.Values{
+		"t":          {name},
+		"nocache":    {strconv.FormatBool(m.NoCache)},
+		"dockerfile": {dfName},
+		"buildargs":  {string(args)},
+	}
+
+	if b.Target != "" {
+		query.Set("target", b.Target)
+	}
+
+	return name, c.build(buildContext, query)
+}
+
+// build sends the build context off to be built, and shows the progress of
+// the build as it goes. Podman reports build errors in the same format as
+// Docker, as a message in the stream, so they're returned as an error.
+func (c *Controller) build(buildContext io.Reader, query url.Values) error {
+	resp, err := c.do("POST", "/build", query, buildContext,
+		"application/x-tar")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return jsonmessage.DisplayJSONMessagesStream(
+		resp.Body,
+		c.stdout.stream,
+		c.stdout.fd,
+		term.IsTerminal(c.stdout.fd),
+		nil,
+	)
+}
@@ -0,0 +1,227 @@
+package podman
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// spec is the subset of Podman's container spec that envctl uses.
+type spec struct {
+	Name         string            `json:"name"`
+	Hostname     string            `json:"hostname,omitempty"`
+	Image        string            `json:"image"`
+	Env          map[string]string `json:"env,omitempty"`
+	User         string            `json:"user,omitempty"`
+	Terminal     bool              `json:"terminal"`
+	Stdin        bool              `json:"stdin"`
+	Mounts       []specMount       `json:"mounts,omitempty"`
+	Volumes      []specVolume      `json:"volumes,omitempty"`
+	PortMappings []portMapping     `json:"portmappings,omitempty"`
+	Resources    *resourceLimits   `json:"resource_limits,omitempty"`
+	ShmSize      int64             `json:"shm_size,omitempty"`
+}
+
+// resourceLimits are the OCI runtime spec's resource limits, which is how
+// Podman takes them.
+type resourceLimits struct {
+	CPU    *cpuLimit `json:"cpu,omitempty"`
+	Memory *limit    `json:"memory,omitempty"`
+	Pids   *limit    `json:"pids,omitempty"`
+}
+
+type cpuLimit struct {
+	Quota  int64  `json:"quota"`
+	Period uint64 `json:"period"`
+}
+
+type limit struct {
+	Limit int64 `json:"limit"`
+}
+
+type specMount struct {
+	Type        string   `json:"type"`
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type specVolume struct {
+	Name    string   `json:"Name"`
+	Dest    string   `json:"Dest"`
+	Options []string `json:"Options,omitempty"`
+}
+
+// portMapping publishes a port. Podman picks a free host port when HostPort
+// is 0.
+type portMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	ContainerPort int    `json:"container_port"`
+	HostPort      int    `json:"host_port,omitempty"`
+	Protocol      string `json:"protocol"`
+}
+
+// Create builds the environment's image and creates a container from it.
+func (c *Controller) Create(m container.Metadata) (container.Metadata, error) {
+	if len(m.Services) > 0 {
+		return container.Metadata{}, errors.New(
+			"services aren't supported by the podman runtime yet")
+	}
+
+	if len(m.Aliases) > 0 || len(m.ExternalNetworks) > 0 {
+		return container.Metadata{}, errors.New(
+			"network aliases and external networks aren't supported by the " +
+				"podman runtime yet")
+	}
+
+	if m.Build != nil {
+		base, err := c.buildBaseImage(m)
+		if err != nil {
+			return container.Metadata{}, err
+		}
+
+		m.BaseImage = base
+	}
+
+	img, err := c.buildImage(m)
+	if err != nil {
+		return container.Metadata{}, err
+	}
+
+	m.ImageID = img
+
+	mounts, volumes := getMounts(m)
+
+	s := spec{
+		Name:         m.BaseName,
+		Hostname:     m.Hostname,
+		Image:        m.ImageID,
+		Env:          getEnv(m.Envs),
+		User:         m.User,
+		Terminal:     true,
+		Stdin:        true,
+		Mounts:       mounts,
+		Volumes:      volumes,
+		PortMappings: getPortMappings(m.Ports),
+		Resources:    getResourceLimits(m.Resources),
+		ShmSize:      m.Resources.ShmSize,
+	}
+
+	var resp struct {
+		ID string `json:"Id"`
+	}
+
+	if err := c.call("POST", "/containers/create", nil, s, &resp); err != nil {
+		return container.Metadata{}, err
+	}
+
+	m.ID = resp.ID
+	return m, nil
+}
+
+// getMounts returns the repo mount and the extra mounts in the given metadata.
+// Podman takes named volumes separately from everything else.
+func getMounts(m container.Metadata) ([]specMount, []specVolume) {
+	mounts := []specMount{
+		{
+			Type:        "bind",
+			Source:      m.Mount.Source,
+			Destination: m.Mount.Destination,
+			Options:     []string{"rbind"},
+		},
+	}
+
+	volumes := []specVolume{}
+
+	for _, mnt := range m.Mounts {
+		opts := []string{}
+		if mnt.ReadOnly {
+			opts = append(opts, "ro")
+		}
+
+		switch mnt.Type {
+		case container.MountVolume:
+			volumes = append(volumes, specVolume{
+				Name:    mnt.Source,
+				Dest:    mnt.Destination,
+				Options: opts,
+			})
+		case container.MountTmpfs:
+			mounts = append(mounts, specMount{
+				Type:        "tmpfs",
+				Source:      "tmpfs",
+				Destination: mnt.Destination,
+				Options:     opts,
+			})
+		default:
+			mounts = append(mounts, specMount{
+				Type:        "bind",
+				Source:      mnt.Source,
+				Destination: mnt.Destination,
+				Options:     append([]string{"rbind"}, opts...),
+			})
+		}
+	}
+
+	return mounts, volumes
+}
+
+func getEnv(envs []string) map[string]string {
+	env := map[string]string{}
+
+	for _, e := range envs {
+		kv := strings.SplitN(e, "=", 2)
+		if len(kv) == 2 {
+			env[kv[0]] = kv[1]
+		}
+	}
+
+	return env
+}
+
+func getPortMappings(ports []container.PortBinding) []portMapping {
+	mappings := []portMapping{}
+
+	for _, p := range ports {
+		mappings = append(mappings, portMapping{
+			HostIP:        p.HostIP,
+			ContainerPort: p.ContainerPort,
+			HostPort:      p.HostPort,
+			Protocol:      p.Protocol,
+		})
+	}
+
+	return mappings
+}
+
+// cpuPeriod is the CFS scheduler period CPU limits are given in, in
+// microseconds. It's the same one Docker uses for its "cpus" option.
+const cpuPeriod = 100000
+
+// getResourceLimits returns the limits for the given resources, or nil if
+// there aren't any.
+func getResourceLimits(res container.Resources) *resourceLimits {
+	if res.NanoCPUs == 0 && res.Memory == 0 && res.PidsLimit == 0 {
+		return nil
+	}
+
+	limits := &resourceLimits{}
+
+	if res.NanoCPUs != 0 {
+		limits.CPU = &cpuLimit{
+			Quota:  res.NanoCPUs * cpuPeriod / 1e9,
+			Period: cpuPeriod,
+		}
+	}
+
+	if res.Memory != 0 {
+		limits.Memory = &limit{Limit: res.Memory}
+	}
+
+	if res.PidsLimit != 0 {
+		limits.Pids = &limit{Limit: res.PidsLimit}
+	}
+
+	return limits
+}
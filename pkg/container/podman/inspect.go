@@ -0,0 +1,93 @@
+package podman
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/go-connections/nat"
+)
+
+// Inspect asks Podman what the container with the given metadata and its
+// image are up to. A container or image that's gone isn't treated as an
+// error, it's reported in the returned state.
+func (c *Controller) Inspect(m container.Metadata) (container.State, error) {
+	var cnt struct {
+		State struct {
+			Status   string
+			Running  bool
+			ExitCode int
+		}
+		NetworkSettings struct {
+			Ports nat.PortMap
+		}
+	}
+
+	err := c.call("GET", "/containers/"+m.ID+"/json", nil, nil, &cnt)
+	if isNotFound(err) {
+		return container.State{Status: container.StatusMissing}, nil
+	}
+
+	if err != nil {
+		return container.State{}, err
+	}
+
+	path := "/images/" + url.PathEscape(m.ImageID) + "/exists"
+	err = c.call("GET", path, nil, nil, nil)
+	if isNotFound(err) {
+		return container.State{Status: container.StatusImageMissing}, nil
+	}
+
+	if err != nil {
+		return container.State{}, err
+	}
+
+	state := container.State{
+		ExitCode: cnt.State.ExitCode,
+		Ports:    getPortBindings(cnt.NetworkSettings.Ports),
+	}
+
+	switch {
+	case cnt.State.Running:
+		state.Status = container.StatusRunning
+	case cnt.State.Status == "created" || cnt.State.Status == "configured":
+		state.Status = container.StatusCreated
+	default:
+		state.Status = container.StatusExited
+	}
+
+	return state, nil
+}
+
+// getPortBindings turns the ports Podman published into bindings, sorted by
+// container port.
+func getPortBindings(ports nat.PortMap) []container.PortBinding {
+	bindings := []container.PortBinding{}
+
+	for port, pbs := range ports {
+		for _, pb := range pbs {
+			hostPort, err := strconv.Atoi(pb.HostPort)
+			if err != nil {
+				continue
+			}
+
+			bindings = append(bindings, container.PortBinding{
+				HostIP:        pb.HostIP,
+				HostPort:      hostPort,
+				ContainerPort: port.Int(),
+				Protocol:      port.Proto(),
+			})
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].ContainerPort != bindings[j].ContainerPort {
+			return bindings[i].ContainerPort < bindings[j].ContainerPort
+		}
+
+		return bindings[i].Protocol < bindings[j].Protocol
+	})
+
+	return bindings
+}
@@ -0,0 +1,277 @@
+// Package podman is a container.Controller implementation for Podman. It talks
+// to Podman's REST API over its unix socket, so it works with rootless Podman
+// without needing a Docker compatible daemon.
+package podman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/pkg/term"
+)
+
+// apiPrefix is prepended to the path of every request to the API. Podman
+// accepts any API version in the path, but the endpoints used here are the
+// ones from version 4.
+const apiPrefix = "/v4.0.0/libpod"
+
+// Controller is a Podman implementation of container.Controller.
+type Controller struct {
+	client *http.Client
+	socket string
+
+	stdin  termStream
+	stdout termStream
+	stderr termStream
+}
+
+type termStream struct {
+	stream *os.File
+	fd     uintptr
+}
+
+// apiError is an error response from the Podman API.
+type apiError struct {
+	Status  int    `json:"response"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("podman: %v (status %v)", e.Message, e.Status)
+}
+
+// init registers Podman as a runtime. Its "socket" option is the path to the
+// Podman socket, which otherwise defaults to the one NewController uses.
+func init() {
+	container.Register("podman", func(opts container.Options) (container.Controller, error) {
+		if socket := opts["socket"]; socket != "" {
+			return NewControllerWithSocket(socket)
+		}
+
+		return NewController()
+	})
+}
+
+// NewController returns a `*Controller` with stdin, stdout and stderr
+// initialized, talking to the Podman socket. The socket is taken from
+// CONTAINER_HOST if it's set to a unix:// URL. Otherwise it's the rootless
+// socket for the current user if there is one, or the system socket.
+func NewController() (*Controller, error) {
+	return NewControllerWithSocket(defaultSocket())
+}
+
+// NewControllerWithSocket returns a `*Controller` like NewController, but
+// talking to the Podman socket at the given path.
+func NewControllerWithSocket(socket string) (*Controller, error) {
+	if socket == "" {
+		return nil, fmt.Errorf("no podman socket found")
+	}
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", socket)
+	}
+
+	stdinfd, _ := term.GetFdInfo(os.Stdin)
+	stdoutfd, _ := term.GetFdInfo(os.Stdout)
+	stderrfd, _ := term.GetFdInfo(os.Stderr)
+
+	return &Controller{
+		client: &http.Client{
+			Transport: &http.Transport{DialContext: dial},
+		},
+		socket: socket,
+		stdin:  termStream{stream: os.Stdin, fd: stdinfd},
+		stdout: termStream{stream: os.Stdout, fd: stdoutfd},
+		stderr: termStream{stream: os.Stderr, fd: stderrfd},
+	}, nil
+}
+
+func defaultSocket() string {
+	if host := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		socket := filepath.Join(dir, "podman", "podman.sock")
+		if _, err := os.Stat(socket); err == nil {
+			return socket
+		}
+	}
+
+	return "/run/podman/podman.sock"
+}
+
+// Ping checks that the Podman socket is reachable.
+func (c *Controller) Ping() error {
+	return c.call("GET", "/_ping", nil, nil, nil)
+}
+
+// do sends a request to the API and returns the response if it was
+// successful. Otherwise, it returns the error the API responded with.
+func (c *Controller) do(
+	method string,
+	path string,
+	query url.Values,
+	body io.Reader,
+	contentType string,
+) (*http.Response, error) {
+	req, err := http.NewRequest(method, apiURL(path, query), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	return resp, nil
+}
+
+// call sends in as JSON to the API, and decodes the response into out, if
+// it's not nil.
+func (c *Controller) call(
+	method string,
+	path string,
+	query url.Values,
+	in interface{},
+	out interface{},
+) error {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(buf)
+	}
+
+	resp, err := c.do(method, path, query, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// hijack sends in, if it's not nil, as JSON to an endpoint that streams, and
+// takes over the connection once the API has responded. Whatever the API
+// streams back can be read from the returned reader, and the returned
+// connection can be written to for stdin.
+func (c *Controller) hijack(
+	path string,
+	query url.Values,
+	in interface{},
+) (net.Conn, *bufio.Reader, error) {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest("POST", apiURL(path, query), body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	conn, err := net.Dial("unix", c.socket)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer conn.Close()
+		return nil, nil, decodeError(resp)
+	}
+
+	return conn, br, nil
+}
+
+func apiURL(path string, query url.Values) string {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     "podman",
+		Path:     apiPrefix + path,
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &apiError{}
+
+	raw, _ := ioutil.ReadAll(resp.Body)
+	if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+
+	apiErr.Status = resp.StatusCode
+	return apiErr
+}
+
+func isNotFound(err error) bool {
+	apiErr, ok := err.(*apiError)
+	return ok && apiErr.Status == http.StatusNotFound
+}
+
+// closeWrite tells the other side of conn that there's nothing left to write,
+// without closing it for reading.
+func closeWrite(conn net.Conn) error {
+	if cw, ok := conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+
+	return nil
+}
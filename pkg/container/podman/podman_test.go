@@ -0,0 +1,471 @@
+package podman
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// fakePodman serves just enough of the Podman API on a unix socket to drive a
+// Controller through an environment's lifecycle.
+type fakePodman struct {
+	srv    *httptest.Server
+	dir    string
+	images map[string]bool
+	// volumes maps the names of volumes to their labels.
+	volumes map[string]map[string]string
+	spec    spec
+	cmd     []string
+	exit    int
+}
+
+func newFakePodman(t *test_pkg.T) *fakePodman {
+	dir, err := ioutil.TempDir("", "envctl-podman")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+
+	l, err := net.Listen("unix", filepath.Join(dir, "podman.sock"))
+	if err != nil {
+		t.Fatal("listening on socket", nil, err)
+	}
+
+	f := &fakePodman{
+		dir:     dir,
+		images:  map[string]bool{},
+		volumes: map[string]map[string]string{},
+	}
+	f.srv = httptest.NewUnstartedServer(http.HandlerFunc(f.serve))
+	f.srv.Listener = l
+	f.srv.Start()
+
+	return f
+}
+
+func (f *fakePodman) Close() {
+	f.srv.Close()
+	os.RemoveAll(f.dir)
+}
+
+func (f *fakePodman) controller(t *test_pkg.T) *Controller {
+	c, err := NewControllerWithSocket(filepath.Join(f.dir, "podman.sock"))
+	if err != nil {
+		t.Fatal("creating controller", nil, err)
+	}
+
+	out, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal("opening output", nil, err)
+	}
+
+	c.stdout = termStream{stream: out, fd: out.Fd()}
+	c.stderr = termStream{stream: out, fd: out.Fd()}
+
+	return c
+}
+
+func (f *fakePodman) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+
+	notFound := func() {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(apiError{
+			Status:  http.StatusNotFound,
+			Message: "no such object",
+		})
+	}
+
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+
+	case path == "/build":
+		ioutil.ReadAll(r.Body)
+		f.images[r.URL.Query().Get("t")] = true
+		w.Write([]byte(`{"stream":"Successfully built\n"}`))
+
+	case path == "/containers/create":
+		json.NewDecoder(r.Body).Decode(&f.spec)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"foocnt"}`))
+
+	case path == "/containers/foocnt/json":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"State": map[string]interface{}{"Status": "running", "Running": true},
+			"NetworkSettings": map[string]interface{}{
+				"Ports": map[string]interface{}{
+					"3000/udp": []map[string]string{{"HostIp": "", "HostPort": "40123"}},
+					"80/tcp":   []map[string]string{{"HostIp": "127.0.0.1", "HostPort": "8080"}},
+				},
+			},
+		})
+
+	case path == "/containers/foocnt/start":
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/containers/foocnt/exec":
+		var cfg execConfig
+		json.NewDecoder(r.Body).Decode(&cfg)
+		f.cmd = cfg.Cmd
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"fooexec"}`))
+
+	case path == "/exec/fooexec/start":
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Write([]byte("HTTP/1.1 200 OK\r\n" +
+			"Content-Type: application/vnd.docker.raw-stream\r\n\r\n"))
+		stdcopy.NewStdWriter(conn, stdcopy.Stdout).Write([]byte("hi\n"))
+		conn.Close()
+
+	case path == "/exec/fooexec/json":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"Running":  false,
+			"ExitCode": f.exit,
+		})
+
+	case strings.HasPrefix(path, "/images/"):
+		name := strings.TrimPrefix(path, "/images/")
+		name = strings.TrimSuffix(name, "/exists")
+		if !f.images[name] {
+			notFound()
+			return
+		}
+
+		if r.Method == "DELETE" {
+			delete(f.images, name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == "/volumes/create":
+		var v volume
+		json.NewDecoder(r.Body).Decode(&v)
+		f.volumes[v.Name] = v.Labels
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(v)
+
+	case path == "/volumes/json":
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+
+		vols := []map[string]interface{}{}
+		for name, labels := range f.volumes {
+			match := true
+			for _, l := range filters["label"] {
+				kv := strings.SplitN(l, "=", 2)
+				match = match && labels[kv[0]] == kv[1]
+			}
+
+			if match {
+				vols = append(vols, map[string]interface{}{
+					"Name":   name,
+					"Labels": labels,
+				})
+			}
+		}
+
+		json.NewEncoder(w).Encode(vols)
+
+	case strings.HasPrefix(path, "/volumes/"):
+		name := strings.TrimPrefix(path, "/volumes/")
+		name = strings.TrimSuffix(name, "/exists")
+		if _, ok := f.volumes[name]; !ok {
+			notFound()
+			return
+		}
+
+		if r.Method == "DELETE" {
+			delete(f.volumes, name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		notFound()
+	}
+}
+
+func TestCreate(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	c := f.controller(&t)
+
+	m, err := c.Create(container.Metadata{
+		BaseName:  "envctl_foo",
+		BaseImage: "alpine",
+		Shell:     "/bin/sh",
+		Hostname:  "app",
+		Envs:      []string{"FOO=bar=baz"},
+		Mount: container.Mount{
+			Source:      "/foo/src",
+			Destination: "/foo/mnt",
+		},
+		Ports: []container.PortBinding{
+			{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+			{ContainerPort: 3000, Protocol: "udp"},
+		},
+		Resources: container.Resources{
+			NanoCPUs: 1500000000,
+			Memory:   1 << 30,
+			ShmSize:  64 << 20,
+		},
+		Mounts: []container.Mount{
+			{Type: container.MountVolume, Source: "m2", Destination: "/root/.m2"},
+			{Type: container.MountTmpfs, Destination: "/tmp", ReadOnly: true},
+		},
+	})
+	if err != nil {
+		t.Fatal("Create()", nil, err)
+	}
+
+	if "foocnt" != m.ID {
+		t.Fatal("container id", "foocnt", m.ID)
+	}
+
+	if !f.images[m.ImageID] {
+		t.Fatal("built image", m.ImageID, f.images)
+	}
+
+	if "app" != f.spec.Hostname {
+		t.Fatal("hostname", "app", f.spec.Hostname)
+	}
+
+	if "bar=baz" != f.spec.Env["FOO"] {
+		t.Fatal("env", "bar=baz", f.spec.Env["FOO"])
+	}
+
+	if len(f.spec.Mounts) != 2 ||
+		"/foo/mnt" != f.spec.Mounts[0].Destination ||
+		"tmpfs" != f.spec.Mounts[1].Type ||
+		"ro" != f.spec.Mounts[1].Options[0] {
+
+		t.Fatal("mounts", "repo and tmpfs", f.spec.Mounts)
+	}
+
+	if len(f.spec.Volumes) != 1 || "m2" != f.spec.Volumes[0].Name {
+		t.Fatal("volumes", "m2", f.spec.Volumes)
+	}
+
+	expected := []portMapping{
+		{HostIP: "127.0.0.1", ContainerPort: 80, HostPort: 8080, Protocol: "tcp"},
+		{ContainerPort: 3000, Protocol: "udp"},
+	}
+
+	if len(expected) != len(f.spec.PortMappings) ||
+		expected[0] != f.spec.PortMappings[0] ||
+		expected[1] != f.spec.PortMappings[1] {
+
+		t.Fatal("port mappings", expected, f.spec.PortMappings)
+	}
+
+	res := f.spec.Resources
+	if res == nil || res.CPU == nil || 150000 != res.CPU.Quota ||
+		100000 != res.CPU.Period || res.Memory == nil ||
+		1<<30 != res.Memory.Limit || res.Pids != nil {
+
+		t.Fatal("resource limits", "1.5 cpus and 1g of memory", res)
+	}
+
+	if 64<<20 != f.spec.ShmSize {
+		t.Fatal("shm size", 64<<20, f.spec.ShmSize)
+	}
+}
+
+func TestCreateUnsupported(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	_, err := f.controller(&t).Create(container.Metadata{
+		BaseName:  "envctl_foo",
+		BaseImage: "alpine",
+		Services:  []container.Service{{Name: "db", Image: "postgres:11"}},
+	})
+
+	expected := "services aren't supported by the podman runtime yet"
+	if err == nil || expected != err.Error() {
+		t.Fatal("Create() error", expected, err)
+	}
+
+	_, err = f.controller(&t).Create(container.Metadata{
+		BaseName:         "envctl_foo",
+		BaseImage:        "alpine",
+		ExternalNetworks: []string{"compose_default"},
+	})
+	if err == nil {
+		t.Fatal("Create() error", "external networks aren't supported", err)
+	}
+
+	if len(f.images) != 0 {
+		t.Fatal("built images", nil, f.images)
+	}
+}
+
+func TestRunExitCode(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	c := f.controller(&t)
+	m := container.Metadata{ID: "foocnt"}
+
+	if err := c.Run(m, []string{"true"}, container.RunOpts{}); err != nil {
+		t.Fatal("Run()", nil, err)
+	}
+
+	f.exit = 3
+	err := c.Run(m, []string{"false"}, container.RunOpts{})
+
+	exitErr, ok := err.(*container.ExitError)
+	if !ok {
+		t.Fatal("Run() error", &container.ExitError{}, err)
+	}
+
+	if 3 != exitErr.Code {
+		t.Fatal("exit code", 3, exitErr.Code)
+	}
+
+	if len(f.cmd) != 1 || "false" != f.cmd[0] {
+		t.Fatal("command", []string{"false"}, f.cmd)
+	}
+}
+
+func TestInspectMissing(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	c := f.controller(&t)
+
+	state, err := c.Inspect(container.Metadata{ID: "barcnt"})
+	if err != nil {
+		t.Fatal("Inspect()", nil, err)
+	}
+
+	if container.StatusMissing != state.Status {
+		t.Fatal("status", container.StatusMissing, state.Status)
+	}
+}
+
+func TestRemoveMissing(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	c := f.controller(&t)
+
+	// Things that are already gone don't keep the rest from being cleaned up.
+	err := c.Remove(container.Metadata{ID: "barcnt", ImageID: "barimg"})
+	if err != nil {
+		t.Fatal("Remove()", nil, err)
+	}
+}
+
+func TestPing(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	c := f.controller(&t)
+
+	if err := c.Ping(); err != nil {
+		t.Fatal("Ping()", nil, err)
+	}
+
+	f.Close()
+
+	if err := c.Ping(); err == nil {
+		t.Fatal("Ping() after shutdown", "error", err)
+	}
+}
+
+func TestVolumes(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	c := f.controller(&t)
+
+	f.volumes["envctl-foo-npm"] = map[string]string{"project": "/src/foo"}
+
+	// The existing volume is left alone.
+	err := c.CreateVolume(container.Volume{Name: "envctl-foo-npm"})
+	if err != nil {
+		t.Fatal("CreateVolume()", nil, err)
+	}
+
+	err = c.CreateVolume(container.Volume{
+		Name:   "envctl-bar-npm",
+		Labels: map[string]string{"project": "/src/bar"},
+	})
+	if err != nil {
+		t.Fatal("CreateVolume()", nil, err)
+	}
+
+	vols, err := c.Volumes(map[string]string{"project": "/src/foo"})
+	if err != nil {
+		t.Fatal("Volumes()", nil, err)
+	}
+
+	if 1 != len(vols) || "envctl-foo-npm" != vols[0].Name {
+		t.Fatal("volumes", "envctl-foo-npm", vols)
+	}
+
+	if "/src/foo" != vols[0].Labels["project"] {
+		t.Fatal("labels", "/src/foo", vols[0].Labels)
+	}
+
+	if err := c.RemoveVolume("envctl-bar-npm"); err != nil {
+		t.Fatal("RemoveVolume()", nil, err)
+	}
+
+	if _, ok := f.volumes["envctl-bar-npm"]; ok {
+		t.Fatal("volume after RemoveVolume()", nil, f.volumes["envctl-bar-npm"])
+	}
+}
+
+func TestInspectPorts(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	f := newFakePodman(&t)
+	defer f.Close()
+
+	c := f.controller(&t)
+	f.images["fooimg"] = true
+
+	state, err := c.Inspect(container.Metadata{ID: "foocnt", ImageID: "fooimg"})
+	if err != nil {
+		t.Fatal("Inspect()", nil, err)
+	}
+
+	expected := []container.PortBinding{
+		{HostIP: "127.0.0.1", HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		{HostPort: 40123, ContainerPort: 3000, Protocol: "udp"},
+	}
+
+	if container.StatusRunning != state.Status {
+		t.Fatal("status", container.StatusRunning, state.Status)
+	}
+
+	if len(expected) != len(state.Ports) ||
+		expected[0] != state.Ports[0] ||
+		expected[1] != state.Ports[1] {
+
+		t.Fatal("published ports", expected, state.Ports)
+	}
+}
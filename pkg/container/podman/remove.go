@@ -0,0 +1,45 @@
+package podman
+
+import (
+	"net/url"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// Remove removes the container with the given metadata, along with its image.
+func (c *Controller) Remove(m container.Metadata) error {
+	query := url.Values{
+		"force": {"true"},
+		"v":     {"true"},
+	}
+
+	err := c.call("DELETE", "/containers/"+m.ID, query, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+
+	if err := c.removeImage(m.ImageID); err != nil {
+		return err
+	}
+
+	// When the base image was built from a Dockerfile, it belongs to the
+	// environment just as much as the image built on top of it.
+	if m.Build != nil {
+		return c.removeImage(m.BaseImage)
+	}
+
+	return nil
+}
+
+func (c *Controller) removeImage(name string) error {
+	query := url.Values{
+		"force": {"true"},
+	}
+
+	err := c.call("DELETE", "/images/"+url.PathEscape(name), query, nil, nil)
+	if isNotFound(err) {
+		return nil
+	}
+
+	return err
+}
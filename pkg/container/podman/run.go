@@ -0,0 +1,119 @@
+package podman
+
+import (
+	"io"
+	"time"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+type execConfig struct {
+	AttachStdin  bool     `json:"AttachStdin"`
+	AttachStdout bool     `json:"AttachStdout"`
+	AttachStderr bool     `json:"AttachStderr"`
+	Cmd          []string `json:"Cmd"`
+	Tty          bool     `json:"Tty"`
+}
+
+type execStart struct {
+	Detach bool `json:"Detach"`
+	Tty    bool `json:"Tty"`
+}
+
+// Run runs the given command array on the container with the given metadata.
+//
+// Without a TTY, the command's stdout and stderr are demultiplexed onto the
+// controller's stdout and stderr. Once the command is done, Run inspects its
+// exit status and returns a *container.ExitError if it's non-zero.
+func (c *Controller) Run(
+	m container.Metadata,
+	cmd []string,
+	opts container.RunOpts,
+) error {
+	tty := opts.TTY
+	stdin := opts.Stdin && !tty
+	if tty {
+		c.mirrorContainerTTY(m.ID)
+	}
+
+	if err := c.Start(m); err != nil {
+		return err
+	}
+
+	cfg := execConfig{
+		AttachStdin:  stdin,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+		Tty:          tty,
+	}
+
+	var resp struct {
+		ID string `json:"Id"`
+	}
+
+	err := c.call("POST", "/containers/"+m.ID+"/exec", nil, cfg, &resp)
+	if err != nil {
+		return err
+	}
+
+	conn, reader, err := c.hijack(
+		"/exec/"+resp.ID+"/start",
+		nil,
+		execStart{Tty: tty},
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if stdin {
+		go func() {
+			io.Copy(conn, c.stdin.stream)
+			closeWrite(conn)
+		}()
+	}
+
+	if tty {
+		_, err = io.Copy(c.stdout.stream, reader)
+	} else {
+		_, err = stdcopy.StdCopy(c.stdout.stream, c.stderr.stream, reader)
+	}
+	if err != nil {
+		return err
+	}
+
+	code, err := c.execExitCode(resp.ID)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return &container.ExitError{Cmd: cmd, Code: code}
+	}
+
+	return nil
+}
+
+// execExitCode waits for the exec with the given ID to finish and returns its
+// exit code. The output stream can close slightly before Podman marks the
+// exec as done, so it polls until that happens.
+func (c *Controller) execExitCode(id string) (int, error) {
+	for {
+		var insp struct {
+			Running  bool
+			ExitCode int
+		}
+
+		if err := c.call("GET", "/exec/"+id+"/json", nil, nil, &insp); err != nil {
+			return 0, err
+		}
+
+		if !insp.Running {
+			return insp.ExitCode, nil
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
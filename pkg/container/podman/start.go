@@ -0,0 +1,12 @@
+package podman
+
+import (
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// Start starts the container with the given metadata back up, with its
+// filesystem as it was when it was stopped. Starting a container that's
+// already running isn't an error.
+func (c *Controller) Start(m container.Metadata) error {
+	return c.call("POST", "/containers/"+m.ID+"/start", nil, nil, nil)
+}
@@ -0,0 +1,18 @@
+package podman
+
+import (
+	"net/url"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+// Stop stops the container with the given metadata without removing it, so
+// that it can be started again later. The container gets 10 seconds to shut
+// down gracefully before it's killed.
+func (c *Controller) Stop(m container.Metadata) error {
+	query := url.Values{
+		"timeout": {"10"},
+	}
+
+	return c.call("POST", "/containers/"+m.ID+"/stop", query, nil, nil)
+}
@@ -0,0 +1,77 @@
+package podman
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+type volume struct {
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Label"`
+}
+
+// CreateVolume creates a named volume, unless there's already one with the
+// same name.
+func (c *Controller) CreateVolume(v container.Volume) error {
+	path := "/volumes/" + url.PathEscape(v.Name) + "/exists"
+	err := c.call("GET", path, nil, nil, nil)
+	if err == nil {
+		return nil
+	}
+
+	if !isNotFound(err) {
+		return err
+	}
+
+	return c.call("POST", "/volumes/create", nil, volume{
+		Name:   v.Name,
+		Labels: v.Labels,
+	}, nil)
+}
+
+// Volumes returns the volumes that have all the given labels.
+func (c *Controller) Volumes(labels map[string]string) ([]container.Volume, error) {
+	query := url.Values{}
+	if len(labels) > 0 {
+		args := []string{}
+		for k, v := range labels {
+			args = append(args, fmt.Sprintf("%v=%v", k, v))
+		}
+
+		raw, err := json.Marshal(map[string][]string{"label": args})
+		if err != nil {
+			return nil, err
+		}
+
+		query.Set("filters", string(raw))
+	}
+
+	// Podman reports volumes' labels under "Labels", even though it takes
+	// them as "Label" when they're created.
+	var resp []struct {
+		Name   string
+		Labels map[string]string
+	}
+
+	if err := c.call("GET", "/volumes/json", query, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	vols := []container.Volume{}
+	for _, v := range resp {
+		vols = append(vols, container.Volume{
+			Name:   v.Name,
+			Labels: v.Labels,
+		})
+	}
+
+	return vols, nil
+}
+
+// RemoveVolume removes the volume with the given name.
+func (c *Controller) RemoveVolume(name string) error {
+	return c.call("DELETE", "/volumes/"+url.PathEscape(name), nil, nil, nil)
+}
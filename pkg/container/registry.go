@@ -0,0 +1,84 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Options are the settings for a runtime, as given in the config file. What
+// they mean is up to each runtime.
+type Options map[string]string
+
+// Factory creates a Controller for a runtime with the given options.
+type Factory func(Options) (Controller, error)
+
+// Pinger is implemented by controllers that can tell whether the runtime they
+// control is reachable.
+type Pinger interface {
+	Ping() error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes a runtime available by name. It's meant to be called from
+// the init function of the package implementing the runtime. Registering the
+// same name twice, or a nil factory, panics.
+func Register(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if f == nil {
+		panic("container: Register factory is nil for runtime " + name)
+	}
+
+	if _, dup := registry[name]; dup {
+		panic("container: Register called twice for runtime " + name)
+	}
+
+	registry[name] = f
+}
+
+// Registered reports whether a runtime has been registered with the given
+// name.
+func Registered(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	_, ok := registry[name]
+	return ok
+}
+
+// Runtimes returns the sorted names of the registered runtimes.
+func Runtimes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := []string{}
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// New creates a Controller for the runtime registered with the given name.
+func New(name string, opts Options) (Controller, error) {
+	registryMu.RLock()
+	f, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown runtime %q", name)
+	}
+
+	if opts == nil {
+		opts = Options{}
+	}
+
+	return f(opts)
+}
@@ -0,0 +1,46 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+type nopCtl struct {
+	Controller
+	opts Options
+}
+
+func TestRegistry(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	Register("registry-test", func(opts Options) (Controller, error) {
+		return nopCtl{opts: opts}, nil
+	})
+
+	if !Registered("registry-test") {
+		t.Fatal("Registered()", true, false)
+	}
+
+	found := false
+	for _, name := range Runtimes() {
+		found = found || name == "registry-test"
+	}
+
+	if !found {
+		t.Fatal("Runtimes()", "registry-test", Runtimes())
+	}
+
+	ctl, err := New("registry-test", Options{"foo": "bar"})
+	if err != nil {
+		t.Fatal("New()", nil, err)
+	}
+
+	if "bar" != ctl.(nopCtl).opts["foo"] {
+		t.Fatal("factory options", "bar", ctl.(nopCtl).opts["foo"])
+	}
+
+	if _, err := New("registry-missing", nil); err == nil {
+		t.Fatal("New() error", "unknown runtime", err)
+	}
+}
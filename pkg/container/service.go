@@ -0,0 +1,41 @@
+package container
+
+import "time"
+
+// Service is a container that runs alongside the environment's, like a
+// database. It's on the same network as the environment, where it can be
+// reached by its Name.
+type Service struct {
+	Name  string   `json:"name"`
+	Image string   `json:"image"`
+	Envs  []string `json:"envs,omitempty"`
+	// Ports are the service's ports to publish on the host.
+	Ports []PortBinding `json:"ports,omitempty"`
+	// Mounts are mounted in the service's container. Anonymous volumes, ones
+	// without a Source, are removed along with the service.
+	Mounts      []Mount      `json:"mounts,omitempty"`
+	Healthcheck *Healthcheck `json:"healthcheck,omitempty"`
+	// DependsOn are the names of the services that have to be healthy before
+	// this one starts. They have to come before it in Metadata.Services.
+	DependsOn []string `json:"depends_on,omitempty"`
+	// ID is the ID of the service's container, once it's been created.
+	ID string `json:"id,omitempty"`
+}
+
+// Healthcheck is how to tell that a service is ready for use. Services
+// without one are ready once they're running, unless their image has a
+// healthcheck of its own.
+type Healthcheck struct {
+	// Test is the command to run in the service's container, in Docker's
+	// form: "CMD" followed by the command's arguments, or "CMD-SHELL"
+	// followed by a command for the container's shell.
+	Test []string `json:"test"`
+	// Interval is the time between checks. The container engine picks it
+	// when it's 0, as it does for Timeout and Retries.
+	Interval time.Duration `json:"interval,omitempty"`
+	// Timeout is how long a check can take before it counts as failed.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Retries is how many checks in a row have to fail for the service to be
+	// unhealthy.
+	Retries int `json:"retries,omitempty"`
+}
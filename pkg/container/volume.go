@@ -0,0 +1,22 @@
+package container
+
+// Volume is a named volume managed by the container engine. Volumes outlive
+// the containers they're mounted in.
+type Volume struct {
+	Name   string
+	Labels map[string]string
+}
+
+// VolumeManager is implemented by controllers that can manage volumes
+// directly, instead of only mounting them.
+type VolumeManager interface {
+	// CreateVolume creates the given volume. Creating a volume that already
+	// exists isn't an error, and leaves what's in it alone.
+	CreateVolume(Volume) error
+	// Volumes returns the volumes that have all the given labels.
+	Volumes(labels map[string]string) ([]Volume, error)
+	// RemoveVolume removes the volume with the given name, along with
+	// everything in it. Volumes that are mounted in a container can't be
+	// removed.
+	RemoveVolume(name string) error
+}
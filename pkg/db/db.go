@@ -0,0 +1,207 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+)
+
+var (
+	// StatusOff is an Environment's status when it hasn't been created yet.
+	StatusOff = 0
+	// StatusReady is an Environment's status when it's ready for use.
+	StatusReady = 1
+	// StatusError is an Environment's status when something is wrong with it.
+	StatusError = 2
+	// StatusStopped is an Environment's status when its container has been
+	// stopped, but not removed.
+	StatusStopped = 3
+)
+
+// DefaultName is the name of the environment used when none is specified.
+const DefaultName = "default"
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Store is anything that can store Environments. Every Environment in a Store
+// is identified by its name.
+type Store interface {
+	Create(name string, e Environment) error
+	Read(name string) (Environment, error)
+	Delete(name string) error
+	List() ([]Environment, error)
+}
+
+// Environment is just a container with its image under the hood. The container
+// is really what runs it. To store it, all that needs to be tracked is the
+// container and the image.
+type Environment struct {
+	Name      string             `json:"name"`
+	Status    int                `json:"status"`
+	Container container.Metadata `json:"container"`
+	// ConfigHash is the hash of the config the environment was created from,
+	// to tell when the config file has changed since. It's empty for
+	// environments created before it was kept track of.
+	ConfigHash string `json:"config_hash,omitempty"`
+}
+
+// JSONStore implements a Store as a directory of JSON files, one per
+// Environment.
+type JSONStore struct {
+	basepath string
+}
+
+// NewJSONStore returns a JSONStore that keeps its files under basepath,
+// creating the directory if it doesn't exist yet.
+func NewJSONStore(basepath string) (js *JSONStore, err error) {
+	js = &JSONStore{
+		basepath: basepath,
+	}
+
+	if _, err = os.Stat(basepath); err != nil {
+		err = os.Mkdir(basepath, os.ModePerm|os.ModeDir)
+		if err != nil {
+			return
+		}
+	}
+
+	// Before environments had names, there was only ever one of them, stored
+	// in "envdata.json". It becomes the default environment.
+	legacy := filepath.Join(basepath, "envdata.json")
+	if _, err = os.Stat(legacy); err == nil {
+		err = os.Rename(legacy, js.path(DefaultName))
+		return
+	}
+
+	return js, nil
+}
+
+// Create writes an Environment to the file for the given name, replacing
+// whatever was there. The directory is created again if deleting the last
+// environment removed it.
+func (js *JSONStore) Create(name string, e Environment) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	e.Name = name
+
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(js.basepath, os.ModePerm|os.ModeDir); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(js.path(name), buf, 0666)
+}
+
+// Read creates an Environment by reading the file for the given name and
+// returns it or an error if something went wrong. If there's no such file, or
+// the JSON Unmarshal returns an error, no error is returned. It's treated as an
+// empty environment. This is because the subsequent call to Create will
+// overwrite what's there when it writes the new Environment.
+func (js *JSONStore) Read(name string) (Environment, error) {
+	if err := checkName(name); err != nil {
+		return Environment{}, err
+	}
+
+	buf, err := ioutil.ReadFile(js.path(name))
+	if os.IsNotExist(err) {
+		return Environment{Name: name}, nil
+	}
+
+	if err != nil {
+		return Environment{}, err
+	}
+
+	var e Environment
+	json.Unmarshal(buf, &e)
+	e.Name = name
+
+	return e, nil
+}
+
+// Delete removes the Environment with the given name. Once there are no
+// environments left, the whole directory is removed.
+func (js *JSONStore) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	err := os.Remove(js.path(name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// This only succeeds if the directory is empty, which is exactly when it
+	// should go away.
+	os.Remove(js.basepath)
+
+	return nil
+}
+
+// List returns every Environment in the store, sorted by name.
+func (js *JSONStore) List() ([]Environment, error) {
+	paths, err := filepath.Glob(filepath.Join(js.basepath, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	envs := []Environment{}
+	for _, p := range paths {
+		e, err := js.Read(strings.TrimSuffix(filepath.Base(p), ".json"))
+		if err != nil {
+			return nil, err
+		}
+
+		envs = append(envs, e)
+	}
+
+	return envs, nil
+}
+
+func (js *JSONStore) path(name string) string {
+	return filepath.Join(js.basepath, name+".json")
+}
+
+func checkName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid environment name %q", name)
+	}
+
+	return nil
+}
+
+// Initialized checks to see if an environment has been initialized. Initialized
+// means any state that isn't "off", including "error" state.
+func (e Environment) Initialized() bool {
+	return e.Status != StatusOff
+}
+
+// StatusName returns the name of the given status, as shown to users.
+func StatusName(status int) string {
+	switch status {
+	case StatusOff:
+		return "off"
+	case StatusReady:
+		return "ready"
+	case StatusError:
+		return "error"
+	case StatusStopped:
+		return "stopped"
+	}
+
+	return "unknown"
+}
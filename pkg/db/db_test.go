@@ -0,0 +1,160 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestJSONStore(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-db")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, ".envctl")
+
+	js, err := NewJSONStore(base)
+	if err != nil {
+		t.Fatal("NewJSONStore()", nil, err)
+	}
+
+	if _, err := os.Stat(base); err != nil {
+		t.Fatal("store directory", "created", err)
+	}
+
+	env, err := js.Read("dev")
+	if err != nil || "dev" != env.Name || env.Initialized() {
+		t.Fatal("reading an environment that was never created", "an empty one", env)
+	}
+
+	for _, name := range []string{"test", "dev"} {
+		err := js.Create(name, Environment{
+			Status:    StatusReady,
+			Container: container.Metadata{ID: name + "cnt"},
+		})
+		if err != nil {
+			t.Fatal("Create()", nil, err)
+		}
+	}
+
+	env, err = js.Read("dev")
+	if err != nil || StatusReady != env.Status || "devcnt" != env.Container.ID {
+		t.Fatal("Read()", "the dev environment", env)
+	}
+
+	envs, err := js.List()
+	if err != nil {
+		t.Fatal("List()", nil, err)
+	}
+
+	if len(envs) != 2 || "dev" != envs[0].Name || "test" != envs[1].Name {
+		t.Fatal("List()", "dev and test", envs)
+	}
+
+	if err := js.Delete("dev"); err != nil {
+		t.Fatal("Delete()", nil, err)
+	}
+
+	if env, _ := js.Read("dev"); env.Initialized() {
+		t.Fatal("deleted environment", "an empty one", env)
+	}
+
+	if _, err := os.Stat(base); err != nil {
+		t.Fatal("store directory with an environment left", "kept", err)
+	}
+
+	// Deleting the last environment removes the directory, and creating
+	// another one brings it back.
+	if err := js.Delete("test"); err != nil {
+		t.Fatal("Delete()", nil, err)
+	}
+
+	if _, err := os.Stat(base); !os.IsNotExist(err) {
+		t.Fatal("store directory after deleting everything", "removed", err)
+	}
+
+	if err := js.Delete("test"); err != nil {
+		t.Fatal("deleting a missing environment", nil, err)
+	}
+
+	if err := js.Create("test", Environment{Status: StatusStopped}); err != nil {
+		t.Fatal("Create() without the directory", nil, err)
+	}
+
+	if env, _ := js.Read("test"); StatusStopped != env.Status {
+		t.Fatal("re-created environment", StatusStopped, env.Status)
+	}
+}
+
+func TestJSONStoreLegacy(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-db")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	legacy := `{"status":1,"container":{"id":"foocnt"}}`
+	err = ioutil.WriteFile(filepath.Join(dir, "envdata.json"), []byte(legacy), 0666)
+	if err != nil {
+		t.Fatal("writing legacy file", nil, err)
+	}
+
+	js, err := NewJSONStore(dir)
+	if err != nil {
+		t.Fatal("NewJSONStore()", nil, err)
+	}
+
+	env, err := js.Read(DefaultName)
+	if err != nil || StatusReady != env.Status || "foocnt" != env.Container.ID {
+		t.Fatal("migrated environment", legacy, env)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "envdata.json")); !os.IsNotExist(err) {
+		t.Fatal("legacy file after migrating", "removed", err)
+	}
+}
+
+func TestJSONStoreNames(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	dir, err := ioutil.TempDir("", "envctl-db")
+	if err != nil {
+		t.Fatal("creating temp dir", nil, err)
+	}
+	defer os.RemoveAll(dir)
+
+	js, err := NewJSONStore(dir)
+	if err != nil {
+		t.Fatal("NewJSONStore()", nil, err)
+	}
+
+	for _, name := range []string{"", "../escape", "a/b", ".hidden", "-flag"} {
+		expected := `invalid environment name "` + name + `"`
+
+		if err := js.Create(name, Environment{}); err == nil || expected != err.Error() {
+			t.Fatal("Create() error for "+name, expected, err)
+		}
+
+		if _, err := js.Read(name); err == nil || expected != err.Error() {
+			t.Fatal("Read() error for "+name, expected, err)
+		}
+
+		if err := js.Delete(name); err == nil || expected != err.Error() {
+			t.Fatal("Delete() error for "+name, expected, err)
+		}
+	}
+
+	if envs, _ := js.List(); len(envs) != 0 {
+		t.Fatal("environments after invalid names", 0, len(envs))
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemStore implements a Store in memory. Nothing in it outlives the process,
+// which makes it handy for tests. It's safe for concurrent use.
+type MemStore struct {
+	mu   sync.Mutex
+	envs map[string]Environment
+}
+
+// NewMemStore returns an empty MemStore.
+func NewMemStore() *MemStore {
+	return &MemStore{
+		envs: map[string]Environment{},
+	}
+}
+
+// Create stores an Environment under the given name, replacing whatever was
+// there.
+func (ms *MemStore) Create(name string, e Environment) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	e.Name = name
+	ms.envs[name] = e
+
+	return nil
+}
+
+// Read returns the Environment with the given name. Like JSONStore, an
+// Environment that was never created isn't an error, it's returned empty.
+func (ms *MemStore) Read(name string) (Environment, error) {
+	if err := checkName(name); err != nil {
+		return Environment{}, err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	e, ok := ms.envs[name]
+	if !ok {
+		return Environment{Name: name}, nil
+	}
+
+	return e, nil
+}
+
+// Delete removes the Environment with the given name.
+func (ms *MemStore) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.envs, name)
+	return nil
+}
+
+// List returns every Environment in the store, sorted by name.
+func (ms *MemStore) List() ([]Environment, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	names := []string{}
+	for name := range ms.envs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	envs := []Environment{}
+	for _, name := range names {
+		envs = append(envs, ms.envs[name])
+	}
+
+	return envs, nil
+}
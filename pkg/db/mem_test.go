@@ -0,0 +1,56 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/UltimateSoftware/envctl/pkg/container"
+	"github.com/UltimateSoftware/envctl/test_pkg"
+)
+
+func TestMemStore(got *testing.T) {
+	t := test_pkg.NewT(got)
+
+	ms := NewMemStore()
+
+	env, err := ms.Read(DefaultName)
+	if err != nil || DefaultName != env.Name || env.Initialized() {
+		t.Fatal("reading an environment that was never created", "an empty one", env)
+	}
+
+	for _, name := range []string{"test", "dev"} {
+		err := ms.Create(name, Environment{
+			Status:    StatusReady,
+			Container: container.Metadata{ID: name + "cnt"},
+		})
+		if err != nil {
+			t.Fatal("Create()", nil, err)
+		}
+	}
+
+	env, err = ms.Read("dev")
+	if err != nil || "dev" != env.Name || "devcnt" != env.Container.ID {
+		t.Fatal("Read()", "the dev environment", env)
+	}
+
+	envs, err := ms.List()
+	if err != nil || len(envs) != 2 || "dev" != envs[0].Name || "test" != envs[1].Name {
+		t.Fatal("List()", "dev and test", envs)
+	}
+
+	if err := ms.Delete("dev"); err != nil {
+		t.Fatal("Delete()", nil, err)
+	}
+
+	if env, _ := ms.Read("dev"); env.Initialized() {
+		t.Fatal("deleted environment", "an empty one", env)
+	}
+
+	if envs, _ := ms.List(); len(envs) != 1 || "test" != envs[0].Name {
+		t.Fatal("List() after Delete()", "test", envs)
+	}
+
+	expected := `invalid environment name "../escape"`
+	if err := ms.Create("../escape", Environment{}); err == nil || expected != err.Error() {
+		t.Fatal("Create() error", expected, err)
+	}
+}